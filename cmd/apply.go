@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply PLANFILE",
+	Short: "Apply a Saved TLSA Plan",
+	Long:  `Apply exactly the changes in a plan file written with --save-plan. Refuses if the records changed since the plan was made.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  resource.ResourceApply,
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	addPreflightFlag(applyCmd)
+	addProviderFlags(applyCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestApplyCmd_Structure(t *testing.T) {
+	if applyCmd == nil {
+		t.Fatal("applyCmd should not be nil")
+	}
+
+	if applyCmd.Name() != "apply" {
+		t.Errorf("Expected name 'apply', got '%s'", applyCmd.Name())
+	}
+
+	if applyCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+
+	if err := applyCmd.Args(applyCmd, []string{}); err == nil {
+		t.Error("Expected apply to require a plan file argument")
+	}
+}
+
+func TestPlanFlags(t *testing.T) {
+	for _, c := range []string{"create", "update", "sync"} {
+		cmd, _, err := rootCmd.Find([]string{c})
+		if err != nil {
+			t.Fatalf("Command '%s' not found: %v", c, err)
+		}
+		for _, flagName := range []string{"dry-run", "save-plan"} {
+			if cmd.Flags().Lookup(flagName) == nil {
+				t.Errorf("Command '%s' missing flag '%s'", c, flagName)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Create or Update TLSA DNS Records from an Inventory File",
+	Long: `Create or update TLSA records for every host in an inventory file.
+
+The inventory is a CSV file with the header zone,subdomain,services,usages,cert.
+Hosts without records are created and hosts with records are updated, all in
+one run. A failed row does not stop the others, and a summary table of every
+row is printed at the end.`,
+	RunE: resource.ResourceBatch,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.Flags().String("inventory", "", "Path to the CSV inventory file (Required)")
+	batchCmd.MarkFlagRequired("inventory")
+	addRecordFlags(batchCmd)
+	addPlanFlags(batchCmd)
+	addOutputFlag(batchCmd)
+	addNotifyFlags(batchCmd)
+	addPreflightFlag(batchCmd)
+	addProviderFlags(batchCmd)
+	addConcurrencyFlag(batchCmd)
+	batchCmd.Flags().Bool("rollover", false, "Perform rolling update of the TLSA records of existing hosts")
+}
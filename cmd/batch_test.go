@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestBatchCmd_Structure(t *testing.T) {
+	if batchCmd.Use != "batch" {
+		t.Errorf("Expected Use 'batch', got '%s'", batchCmd.Use)
+	}
+
+	if batchCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestBatchCmd_Flags(t *testing.T) {
+	expectedFlags := []string{"inventory", "ttl", "comment", "tag", "dry-run", "output", "rollover", "concurrency", "skip-preflight", "zone-file"}
+
+	for _, flagName := range expectedFlags {
+		if batchCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+
+	// Inventory rows replace the per-host flags
+	for _, flagName := range []string{"url", "cert", "config"} {
+		if batchCmd.Flags().Lookup(flagName) != nil {
+			t.Errorf("Expected no flag '%s'", flagName)
+		}
+	}
+}
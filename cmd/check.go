@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check Published TLSA DNS Records Against Certificate",
+	Long: `Check that the published TLSA DNS Records match the certificate on disk, without changing anything.
+Exits 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN) for use as a Nagios/Icinga plugin.`,
+	RunE:          resource.ResourceCheck,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	addCommonFlags(checkCmd)
+	addProviderFlags(checkCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestCheckCmd_Structure(t *testing.T) {
+	if checkCmd == nil {
+		t.Fatal("checkCmd should not be nil")
+	}
+
+	if checkCmd.Use != "check" {
+		t.Errorf("Expected Use 'check', got '%s'", checkCmd.Use)
+	}
+
+	if checkCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+
+	if !checkCmd.SilenceUsage || !checkCmd.SilenceErrors {
+		t.Error("Expected check to print only its own plugin output")
+	}
+}
+
+func TestCheckCmd_HasCommonFlags(t *testing.T) {
+	commonFlags := []string{"url", "subdomain", "cert", "tcp25", "tcp465", "tcp587", "tcp-port", "dane-ta", "config"}
+
+	for _, flagName := range commonFlags {
+		if checkCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Check command missing common flag '%s'", flagName)
+		}
+	}
+}
+
+func TestCheckCmd_SetupErrorsAreUnknown(t *testing.T) {
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.PersistentFlags().Set("log-level", "info")
+	})
+
+	for _, args := range [][]string{
+		{"check", "--no-such-flag"},
+		{"check", "--log-level", "loud"},
+	} {
+		rootCmd.SetArgs(args)
+		if code := ExitCode(Execute()); code != 3 {
+			t.Errorf("%v: expected exit code 3 (UNKNOWN), got %d", args, code)
+		}
+	}
+}
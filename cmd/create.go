@@ -14,9 +14,9 @@ var createCmd = &cobra.Command{
 }
 
 func addCommonFlags(cmd *cobra.Command) {
-	cmd.Flags().StringP("url", "u", "", "Domain to Update (Required)")
-	cmd.Flags().StringP("subdomain", "s", "", "TLSA Subdomain (Required)")
-	cmd.Flags().StringP("cert", "f", "", "Path to Certificate File, fullchain if dane-ta is true (Required)")
+	cmd.Flags().StringP("url", "u", "", "Domain to Update (Required unless --config)")
+	cmd.Flags().StringP("subdomain", "s", "", "TLSA Subdomain, may have several labels, or @ for the zone apex (Required unless --config)")
+	cmd.Flags().StringP("cert", "f", "", "Path to Certificate File, fullchain if dane-ta is true (Required unless --config)")
 	cmd.Flags().BoolP("tcp25", "t", false, "Port 25/TCP")
 	cmd.Flags().BoolP("tcp465", "p", false, "Port 465/TCP")
 	cmd.Flags().BoolP("tcp587", "e", false, "Port 587/TCP")
@@ -26,12 +26,83 @@ func addCommonFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolP("dane-ta", "", false, "Create DANE-TA (2 0 1) record")
 	cmd.Flags().IntP("selector", "l", -1, "TLSA selector (0 = Full cert, 1 = SubjectPublicKeyInfo). If not specified, defaults to 1 for DANE-EE and 0 for DANE-TA")
 	cmd.Flags().IntP("matching-type", "m", 1, "TLSA matching type (1 = SHA2-256, 2 = SHA2-512)")
-	cmd.MarkFlagRequired("url")
-	cmd.MarkFlagRequired("subdomain")
-	cmd.MarkFlagRequired("cert")
+	cmd.Flags().String("config", "", "Path to YAML config file describing all managed TLSA records, replaces the flags above")
+	cmd.MarkFlagsMutuallyExclusive("config", "url")
+	cmd.MarkFlagsMutuallyExclusive("config", "subdomain")
+	cmd.MarkFlagsMutuallyExclusive("config", "cert")
+	addDiscoveryFlags(cmd)
+}
+
+// addDiscoveryFlags adds the flags for publishing at the MX and SRV targets
+// of a domain instead of a single host, and at the targets of CNAMEs.
+func addDiscoveryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("from-mx", "", "Publish at each MX target of this domain (port 25) in a zone you control, replaces --url, --subdomain and the port flags")
+	cmd.Flags().StringSlice("srv", nil, "With --from-mx, also publish at the targets of these SRV services: submission, submissions, imap, imaps, pop3s, xmpp-client, xmpp-server or _service._proto (repeatable)")
+	cmd.Flags().String("resolver", "", "DNS server for --from-mx and CNAME lookups as host:port (default the first nameserver in /etc/resolv.conf)")
+	cmd.Flags().String("cname", "off", "When a host is a CNAME: do not check, warn where clients look for TLSA records, publish at the CNAME target, or both (off, warn, target, both)")
+	for _, name := range []string{"config", "url", "subdomain", "tcp25", "tcp465", "tcp587", "tcp-port"} {
+		cmd.MarkFlagsMutuallyExclusive("from-mx", name)
+	}
+}
+
+// addRecordFlags adds the flags for the TTL, comment and tags written with
+// each record. They override the values in --config.
+func addRecordFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ttl", "3600", "TTL of the records in seconds (60-86400), or auto")
+	cmd.Flags().String("comment", "", "Go template for the record comment, with .Action, .Name, .Usage, .Time, .Serial, .Subject and .NotAfter (default \"{{.Action}} by GoTLSAFlare - {{.Time}}\")")
+	cmd.Flags().StringSlice("tag", nil, "Cloudflare record tag as name:value (repeatable)")
+}
+
+// addConcurrencyFlag adds the flag limiting how many zones are updated at
+// once.
+func addConcurrencyFlag(cmd *cobra.Command) {
+	cmd.Flags().Int("concurrency", 4, "Number of zones to update at once. The records of one zone are written in one batch request, or one at a time if the batch endpoint is unavailable. With --rollover all old records are deleted after one shared wait")
+}
+
+// addPlanFlags adds the flags for previewing changes instead of making them.
+func addPlanFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "Show the changes that would be made without making them")
+	cmd.Flags().String("save-plan", "", "Write the changes to a plan file for \"gotlsaflare apply\" instead of making them")
+}
+
+// addProviderFlags adds the flags for keeping records in a zone file instead
+// of Cloudflare.
+func addProviderFlags(cmd *cobra.Command) {
+	cmd.Flags().String("zone-file", "", "Write TLSA records to this zone file or $INCLUDE snippet instead of Cloudflare")
+	cmd.Flags().String("zone-origin", "", "Zone name of --zone-file (default the SOA owner or first $ORIGIN in the file)")
+}
+
+// addPreflightFlag adds the flag for skipping the token and access check
+// made before any record is changed.
+func addPreflightFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("skip-preflight", false, "Do not verify the token and DNS edit access on the zone before making changes")
+}
+
+// addOutputFlag adds the flag selecting how results are written to stdout.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "text", "Output format (text, json). json writes a single result document to stdout")
+}
+
+// addNotifyFlags adds the flags configuring notifications about published
+// records and rollovers.
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("notify-webhook", nil, "POST a JSON event to this URL (repeatable)")
+	cmd.Flags().StringSlice("notify-slack", nil, "Post a message to this Slack-compatible webhook URL (repeatable)")
+	cmd.Flags().StringSlice("notify-exec", nil, "Run this script with the event in GOTLSAFLARE_* variables and as JSON on stdin (repeatable)")
+	cmd.Flags().StringSlice("notify-email", nil, "Email the event to this address (repeatable, requires --smtp-server and --smtp-from)")
+	cmd.Flags().String("smtp-server", "", "SMTP server for --notify-email as host:port, password read from SMTP_PASSWORD")
+	cmd.Flags().String("smtp-from", "", "Sender address for --notify-email")
+	cmd.Flags().String("smtp-username", "", "SMTP username for --notify-email")
+	cmd.Flags().StringSlice("notify-on", nil, "Only notify on these events (published, failed, rollover_finalized, propagation_failed, old_record_preserved), default all")
 }
 
 func init() {
 	rootCmd.AddCommand(createCmd)
 	addCommonFlags(createCmd)
+	addRecordFlags(createCmd)
+	addPlanFlags(createCmd)
+	addOutputFlag(createCmd)
+	addNotifyFlags(createCmd)
+	addPreflightFlag(createCmd)
+	addProviderFlags(createCmd)
 }
@@ -39,6 +39,12 @@ func TestCreateCmd_Flags(t *testing.T) {
 		"dane-ta",
 		"selector",
 		"matching-type",
+		"output",
+		"notify-webhook",
+		"from-mx",
+		"srv",
+		"resolver",
+		"cname",
 	}
 
 	for _, flagName := range expectedFlags {
@@ -97,6 +103,13 @@ func TestCreateCmd_FlagDefaults(t *testing.T) {
 	}
 }
 
+func TestCreateCmd_CNAMEDefaultsOff(t *testing.T) {
+	// CNAME lookups are opt-in so runs do not depend on DNS by default
+	if flag := createCmd.Flags().Lookup("cname"); flag == nil || flag.DefValue != "off" {
+		t.Errorf("Expected --cname to default to off, got %v", flag)
+	}
+}
+
 func TestAddCommonFlags(t *testing.T) {
 	// Test that addCommonFlags works properly
 	testCmd := &cobra.Command{
@@ -109,7 +122,7 @@ func TestAddCommonFlags(t *testing.T) {
 	// Verify all expected flags are added
 	expectedFlags := []string{
 		"url", "subdomain", "cert", "tcp25", "tcp465", "tcp587",
-		"tcp-port", "dane-ee", "no-dane-ee", "dane-ta", "selector", "matching-type",
+		"tcp-port", "dane-ee", "no-dane-ee", "dane-ta", "selector", "matching-type", "config",
 	}
 
 	for _, flagName := range expectedFlags {
@@ -118,3 +131,17 @@ func TestAddCommonFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordFlags(t *testing.T) {
+	for _, c := range []string{"create", "update", "watch", "sync"} {
+		cmd, _, err := rootCmd.Find([]string{c})
+		if err != nil {
+			t.Fatalf("Command '%s' not found: %v", c, err)
+		}
+		for _, name := range []string{"ttl", "comment", "tag"} {
+			if cmd.Flags().Lookup(name) == nil {
+				t.Errorf("Command '%s' missing flag '%s'", c, name)
+			}
+		}
+	}
+}
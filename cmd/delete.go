@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete TLSA DNS Record",
+	Long:  `Delete TLSA DNS Records, e.g. when decommissioning a host. Only records managed by gotlsaflare are deleted unless --force is given.`,
+	RunE:  resource.ResourceDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+	addCommonFlags(deleteCmd)
+	addPlanFlags(deleteCmd)
+	addPreflightFlag(deleteCmd)
+	addProviderFlags(deleteCmd)
+	deleteCmd.Flags().Lookup("cert").Usage = "Only delete records matching this certificate"
+	deleteCmd.Flags().Bool("all", false, "Delete all TLSA records of the subdomain, regardless of port and usage")
+	deleteCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	deleteCmd.Flags().Bool("force", false, "Also delete TLSA records not created by gotlsaflare")
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestDeleteCmd_Structure(t *testing.T) {
+	if deleteCmd == nil {
+		t.Fatal("deleteCmd should not be nil")
+	}
+
+	if deleteCmd.Use != "delete" {
+		t.Errorf("Expected Use 'delete', got '%s'", deleteCmd.Use)
+	}
+
+	if deleteCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestDeleteCmd_Flags(t *testing.T) {
+	expectedFlags := []string{"url", "subdomain", "cert", "tcp25", "dane-ta", "all", "yes", "force", "dry-run"}
+
+	for _, flagName := range expectedFlags {
+		if deleteCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+}
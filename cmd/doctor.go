@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check Cloudflare Credentials, Zone Access and DNSSEC",
+	Long:  `Check that the Cloudflare credentials are valid, which zones they can see, whether TLSA records in them can be read, whether they can be written (probed with a best-effort heuristic), and whether DNSSEC is active`,
+	RunE:  resource.ResourceDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringSliceP("url", "u", nil, "Zone to check (repeatable), default every zone visible to the token")
+}
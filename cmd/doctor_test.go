@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestDoctorCmd_Structure(t *testing.T) {
+	if doctorCmd == nil {
+		t.Fatal("doctorCmd should not be nil")
+	}
+
+	if doctorCmd.Use != "doctor" {
+		t.Errorf("Expected Use 'doctor', got '%s'", doctorCmd.Use)
+	}
+
+	if doctorCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+
+	flag := doctorCmd.Flags().Lookup("url")
+	if flag == nil || flag.Value.Type() != "stringSlice" {
+		t.Error("Expected repeatable 'url' flag")
+	}
+}
+
+func TestPreflightFlag(t *testing.T) {
+	for _, c := range []string{"create", "update", "watch", "sync", "delete", "apply"} {
+		cmd, _, err := rootCmd.Find([]string{c})
+		if err != nil {
+			t.Fatalf("Command '%s' not found: %v", c, err)
+		}
+		if cmd.Flags().Lookup("skip-preflight") == nil {
+			t.Errorf("Command '%s' missing flag 'skip-preflight'", c)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export TLSA DNS Records",
+	Long:  `Export the TLSA DNS Records computed from the certificates, or fetched from Cloudflare with --from-provider, as BIND zone-file lines, JSON or YAML`,
+	RunE:  resource.ResourceExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	addCommonFlags(exportCmd)
+	addRecordFlags(exportCmd)
+	exportCmd.Flags().String("format", "bind", "Export format (bind, json, yaml)")
+	exportCmd.Flags().Bool("from-provider", false, "Export the records published in the zone of --url instead of computing them from certificates")
+	exportCmd.Flags().Bool("all", false, "With --from-provider, also export TLSA records not managed by gotlsaflare")
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestExportCmd_Structure(t *testing.T) {
+	if exportCmd.Use != "export" {
+		t.Errorf("Expected Use 'export', got '%s'", exportCmd.Use)
+	}
+
+	if exportCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestExportCmd_Flags(t *testing.T) {
+	expectedFlags := []string{"url", "subdomain", "cert", "config", "ttl", "format", "from-provider", "all"}
+
+	for _, flagName := range expectedFlags {
+		if exportCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+
+	if exportCmd.Flags().Lookup("format").DefValue != "bind" {
+		t.Errorf("Expected default format 'bind', got '%s'", exportCmd.Flags().Lookup("format").DefValue)
+	}
+}
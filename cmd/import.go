@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Adopt Existing TLSA DNS Records",
+	Long:  `Adopt TLSA DNS Records created by hand that match the certificates, marking them as managed by gotlsaflare so sync and delete can manage them`,
+	RunE:  resource.ResourceImport,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	addCommonFlags(importCmd)
+	addRecordFlags(importCmd)
+	addPlanFlags(importCmd)
+	addPreflightFlag(importCmd)
+	addProviderFlags(importCmd)
+	importCmd.Flags().Lookup("ttl").Usage = "Also set the TTL of adopted records, in seconds (60-86400) or auto. By default their TTL is kept"
+}
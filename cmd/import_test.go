@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestImportCmd_Structure(t *testing.T) {
+	if importCmd.Use != "import" {
+		t.Errorf("Expected Use 'import', got '%s'", importCmd.Use)
+	}
+
+	if importCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestImportCmd_Flags(t *testing.T) {
+	expectedFlags := []string{"url", "subdomain", "cert", "config", "comment", "tag", "ttl", "dry-run", "skip-preflight", "zone-file"}
+
+	for _, flagName := range expectedFlags {
+		if importCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+}
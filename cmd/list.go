@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List TLSA DNS Records",
+	Long:  `List the TLSA DNS Records of a zone, or of a single host with --subdomain`,
+	RunE:  resource.ResourceList,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringP("url", "u", "", "Domain to list (Required)")
+	listCmd.Flags().StringP("subdomain", "s", "", "Only list TLSA records for this subdomain, or @ for the zone apex")
+	listCmd.Flags().StringP("output", "o", "table", "Output format (table, json, yaml)")
+	listCmd.Flags().Bool("no-trunc", false, "Do not truncate certificate hashes in table output")
+	listCmd.MarkFlagRequired("url")
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestListCmd_Structure(t *testing.T) {
+	if listCmd == nil {
+		t.Fatal("listCmd should not be nil")
+	}
+
+	if listCmd.Use != "list" {
+		t.Errorf("Expected Use 'list', got '%s'", listCmd.Use)
+	}
+
+	if listCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestListCmd_Flags(t *testing.T) {
+	expectedFlags := []string{"url", "subdomain", "output", "no-trunc"}
+
+	for _, flagName := range expectedFlags {
+		if listCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+
+	if listCmd.Flags().Lookup("output").DefValue != "table" {
+		t.Errorf("Expected default output 'table', got '%s'", listCmd.Flags().Lookup("output").DefValue)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var mtaSTSCmd = &cobra.Command{
+	Use:   "mta-sts",
+	Short: "Publish MTA-STS Policy Record and Generate Policy File",
+	Long: `Publish the _mta-sts TXT record of a mail domain and generate the
+mta-sts.txt policy file listing its MX hosts. The record id is derived from
+the policy, so it changes whenever the policy does. The policy file must be
+served at https://mta-sts.<domain>/.well-known/mta-sts.txt.`,
+	RunE: resource.ResourceMTASTS,
+}
+
+var tlsRPTCmd = &cobra.Command{
+	Use:   "tls-rpt",
+	Short: "Publish SMTP TLS Reporting (TLS-RPT) Record",
+	Long:  `Publish the _smtp._tls TXT record asking senders to report TLS failures, including DANE and MTA-STS failures, to the given addresses`,
+	RunE:  resource.ResourceTLSRPT,
+}
+
+// addTXTFlags adds the flags shared by the commands managing TXT records
+// next to the TLSA records.
+func addTXTFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("url", "u", "", "Mail domain (Required)")
+	cmd.MarkFlagRequired("url")
+	cmd.Flags().String("ttl", "3600", "TTL of the record in seconds (60-86400), or auto")
+	cmd.Flags().Bool("dry-run", false, "Show the change that would be made without making it")
+}
+
+func init() {
+	rootCmd.AddCommand(mtaSTSCmd)
+	addTXTFlags(mtaSTSCmd)
+	mtaSTSCmd.Flags().String("mode", "testing", "Policy mode (enforce, testing, none)")
+	mtaSTSCmd.Flags().Int("max-age", 604800, "Seconds senders may cache the policy (up to 31557600)")
+	mtaSTSCmd.Flags().StringSlice("mx", nil, "MX host to list in the policy, may be a *. wildcard (repeatable, default the MX records of --url)")
+	mtaSTSCmd.Flags().String("resolver", "", "DNS server for the MX lookup as host:port (default the first nameserver in /etc/resolv.conf)")
+	mtaSTSCmd.Flags().String("policy-file", "", "Write the policy to this file instead of stdout")
+
+	rootCmd.AddCommand(tlsRPTCmd)
+	addTXTFlags(tlsRPTCmd)
+	tlsRPTCmd.Flags().StringSlice("rua", nil, "Report destination, mailto: or https:// URI (repeatable, Required)")
+	tlsRPTCmd.MarkFlagRequired("rua")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestMTASTSCmd_Structure(t *testing.T) {
+	if mtaSTSCmd.Use != "mta-sts" {
+		t.Errorf("Expected Use 'mta-sts', got '%s'", mtaSTSCmd.Use)
+	}
+
+	if mtaSTSCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestMTASTSCmd_Flags(t *testing.T) {
+	expectedFlags := []string{"url", "ttl", "dry-run", "mode", "max-age", "mx", "resolver", "policy-file"}
+
+	for _, flagName := range expectedFlags {
+		if mtaSTSCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+
+	if mtaSTSCmd.Flags().Lookup("mode").DefValue != "testing" {
+		t.Errorf("Expected default mode 'testing', got '%s'", mtaSTSCmd.Flags().Lookup("mode").DefValue)
+	}
+}
+
+func TestTLSRPTCmd_Flags(t *testing.T) {
+	if tlsRPTCmd.Use != "tls-rpt" || tlsRPTCmd.RunE == nil {
+		t.Errorf("Unexpected tls-rpt command: %s", tlsRPTCmd.Use)
+	}
+
+	for _, flagName := range []string{"url", "ttl", "dry-run", "rua"} {
+		if tlsRPTCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+}
@@ -1,18 +1,69 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"gotlsaflare/resource"
+	"os"
+
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "gotlsaflare",
 	Short: "Go binary for updating TLSA DANE record on cloudflare from x509 Certificate.",
+
+	// Errors are printed by Execute so credentials can be redacted first
+	SilenceErrors: true,
+
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := resource.ConfigureLogging(cmd, args); err != nil {
+			return err
+		}
+		if err := resource.ConfigureCredentials(cmd, args); err != nil {
+			return err
+		}
+		return resource.ConfigureProvider(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("log-format", "text", "Log format (text, json)")
+	rootCmd.PersistentFlags().String("log-level", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().String("token-file", "", "Read the Cloudflare API token from this file instead of TOKEN (relative paths are looked up in $CREDENTIALS_DIRECTORY first)")
+	rootCmd.PersistentFlags().String("profile", "", "Use this profile from the credentials file (or set GOTLSAFLARE_PROFILE)")
+	rootCmd.PersistentFlags().String("credentials-file", "", "Credentials file with named profiles (default $XDG_CONFIG_HOME/gotlsaflare/credentials.yaml)")
 }
 
 func Execute() error {
-	err := rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
 	if err != nil {
+		if cmd == checkCmd {
+			// Flag, config and credential errors are UNKNOWN to a monitoring
+			// system, not WARNING
+			err = resource.CheckError(err)
+		}
+		printError(err)
 		return err
 	}
 	return nil
 }
+
+// printError writes the error to stderr with credentials redacted. Errors
+// carrying an exit code have already been reported by their command.
+func printError(err error) {
+	var exitErr *resource.ExitError
+	if errors.As(err, &exitErr) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Error:", resource.Redact(err.Error()))
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	var exitErr *resource.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"gotlsaflare/resource"
 	"testing"
 )
 
@@ -55,3 +58,42 @@ func TestRootCmd_HasCommands(t *testing.T) {
 		}
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(errors.New("plain error")); code != 1 {
+		t.Errorf("Expected exit code 1 for plain error, got %d", code)
+	}
+
+	err := fmt.Errorf("wrapped: %w", &resource.ExitError{Code: 2, Err: errors.New("critical")})
+	if code := ExitCode(err); code != 2 {
+		t.Errorf("Expected exit code 2 for ExitError, got %d", code)
+	}
+}
+
+func TestRootCmd_PersistentFlags(t *testing.T) {
+	testCases := []struct {
+		flag         string
+		defaultValue string
+	}{
+		{"log-format", "text"},
+		{"log-level", "info"},
+		{"token-file", ""},
+		{"profile", ""},
+		{"credentials-file", ""},
+	}
+
+	for _, tc := range testCases {
+		flag := rootCmd.PersistentFlags().Lookup(tc.flag)
+		if flag == nil {
+			t.Errorf("Expected persistent flag '%s' to exist", tc.flag)
+			continue
+		}
+		if flag.DefValue != tc.defaultValue {
+			t.Errorf("Flag '%s': expected default '%s', got '%s'", tc.flag, tc.defaultValue, flag.DefValue)
+		}
+	}
+
+	if rootCmd.PersistentPreRunE == nil {
+		t.Error("Expected rootCmd to configure logging and credentials before running commands")
+	}
+}
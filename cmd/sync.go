@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Reconcile TLSA DNS Records with Certificates",
+	Long:  `Create, update or delete TLSA DNS Records managed by gotlsaflare so they match the certificates. Safe to run repeatedly.`,
+	RunE:  resource.ResourceSync,
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	addCommonFlags(syncCmd)
+	addRecordFlags(syncCmd)
+	addPlanFlags(syncCmd)
+	addPreflightFlag(syncCmd)
+	addProviderFlags(syncCmd)
+	syncCmd.Flags().Bool("prune", false, "Also delete managed TLSA records of other hosts in the same zones")
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestSyncCmd_Structure(t *testing.T) {
+	if syncCmd == nil {
+		t.Fatal("syncCmd should not be nil")
+	}
+
+	if syncCmd.Use != "sync" {
+		t.Errorf("Expected Use 'sync', got '%s'", syncCmd.Use)
+	}
+
+	if syncCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestSyncCmd_Flags(t *testing.T) {
+	expectedFlags := []string{"url", "subdomain", "cert", "tcp25", "config", "prune"}
+
+	for _, flagName := range expectedFlags {
+		if syncCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist", flagName)
+		}
+	}
+}
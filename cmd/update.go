@@ -16,5 +16,14 @@ var updateCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(updateCmd)
 	addCommonFlags(updateCmd)
+	addRecordFlags(updateCmd)
+	addPlanFlags(updateCmd)
+	addOutputFlag(updateCmd)
+	addNotifyFlags(updateCmd)
+	addPreflightFlag(updateCmd)
+	addProviderFlags(updateCmd)
+	addConcurrencyFlag(updateCmd)
 	updateCmd.Flags().Bool("rollover", false, "Perform rolling update of TLSA records")
+	updateCmd.Flags().Bool("rollback-on-error", false, "Restore every record changed by the run if any record fails")
+	updateCmd.Flags().String("metrics-listen", "", "Serve Prometheus metrics on this address while running, e.g. :9115")
 }
@@ -36,8 +36,19 @@ func TestUpdateCmd_Flags(t *testing.T) {
 		"no-dane-ee",
 		"dane-ta",
 		"rollover",
+		"rollback-on-error",
+		"zone-file",
+		"zone-origin",
 		"selector",
 		"matching-type",
+		"metrics-listen",
+		"concurrency",
+		"output",
+		"notify-webhook",
+		"notify-slack",
+		"notify-exec",
+		"notify-email",
+		"notify-on",
 	}
 
 	for _, flagName := range expectedFlags {
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"gotlsaflare/resource"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch Certificate Files and Update TLSA DNS Record on Change",
+	Long:  `Watch certificate files and update the TLSA DNS Record whenever the certificate changes`,
+	RunE:  resource.ResourceWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	addCommonFlags(watchCmd)
+	addRecordFlags(watchCmd)
+	addNotifyFlags(watchCmd)
+	addPreflightFlag(watchCmd)
+	addProviderFlags(watchCmd)
+	addConcurrencyFlag(watchCmd)
+	watchCmd.Flags().Bool("rollover", false, "Perform rolling update of TLSA records")
+	watchCmd.Flags().Bool("rollback-on-error", false, "Restore every record changed by the run if any record fails")
+	watchCmd.Flags().String("metrics-listen", "", "Serve Prometheus metrics on this address while running, e.g. :9115")
+	watchCmd.Flags().StringSlice("watch-path", nil, "Additional file to watch for changes (repeatable)")
+	watchCmd.Flags().Duration("debounce", 10*time.Second, "Quiet period after the last file change before publishing")
+	watchCmd.Flags().Duration("poll-interval", time.Minute, "Interval for polling the certificate when file notifications are missed (0 disables)")
+	watchCmd.Flags().Bool("on-start", false, "Publish once at startup if the published records do not match the certificate")
+}
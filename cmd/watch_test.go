@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestWatchCmd_Structure(t *testing.T) {
+	if watchCmd == nil {
+		t.Fatal("watchCmd should not be nil")
+	}
+
+	if watchCmd.Use != "watch" {
+		t.Errorf("Expected Use 'watch', got '%s'", watchCmd.Use)
+	}
+
+	if watchCmd.RunE == nil {
+		t.Error("Expected RunE to be set")
+	}
+}
+
+func TestWatchCmd_Flags(t *testing.T) {
+	testCases := []struct {
+		flag         string
+		expectedType string
+	}{
+		{"url", "string"},
+		{"subdomain", "string"},
+		{"cert", "string"},
+		{"tcp25", "bool"},
+		{"rollover", "bool"},
+		{"rollback-on-error", "bool"},
+		{"watch-path", "stringSlice"},
+		{"debounce", "duration"},
+		{"poll-interval", "duration"},
+		{"on-start", "bool"},
+		{"metrics-listen", "string"},
+		{"concurrency", "int"},
+		{"notify-webhook", "stringSlice"},
+		{"notify-email", "stringSlice"},
+		{"smtp-server", "string"},
+	}
+
+	for _, tc := range testCases {
+		flag := watchCmd.Flags().Lookup(tc.flag)
+		if flag == nil {
+			t.Errorf("Expected flag '%s' to exist", tc.flag)
+			continue
+		}
+
+		if flag.Value.Type() != tc.expectedType {
+			t.Errorf("Flag '%s': expected type '%s', got '%s'", tc.flag, tc.expectedType, flag.Value.Type())
+		}
+	}
+}
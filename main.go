@@ -8,7 +8,7 @@ import (
 func main() {
 	err := cmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 
 	os.Exit(0)
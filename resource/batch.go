@@ -0,0 +1,111 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// recordBatch is a set of changes to one zone sent to the dns_records/batch
+// endpoint. Cloudflare applies the deletes, then the puts, then the posts,
+// and rolls all of them back if any fails.
+type recordBatch struct {
+	Deletes []batchDelete `json:"deletes,omitempty"`
+	Puts    []batchPut    `json:"puts,omitempty"`
+	Posts   []JSONRequest `json:"posts,omitempty"`
+}
+
+type batchDelete struct {
+	ID string `json:"id"`
+}
+
+type batchPut struct {
+	ID string `json:"id"`
+	JSONRequest
+}
+
+// batchResult holds the records of each part of a batch, in request order.
+type batchResult struct {
+	Deletes []DNSRecord `json:"deletes"`
+	Puts    []DNSRecord `json:"puts"`
+	Posts   []DNSRecord `json:"posts"`
+}
+
+func (b recordBatch) empty() bool {
+	return len(b.Deletes)+len(b.Puts)+len(b.Posts) == 0
+}
+
+// applyBatch sends the changes in a single atomic request. If the account
+// cannot use the batch endpoint they are made one by one instead, stopping at
+// the first failure. The result then holds the changes made before the
+// failure, so callers can tell which ones took effect. It is never nil.
+func applyBatch(zoneID string, auth cloudflareAuth, batch recordBatch) (*batchResult, error) {
+	if batch.empty() {
+		return &batchResult{}, nil
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return &batchResult{}, fmt.Errorf("error encoding batch: %v", err)
+	}
+
+	path := "/zones/" + zoneID + "/dns_records/batch"
+	status, respBody, err := cloudflareRequest("POST", path, auth, body)
+	if err != nil {
+		return &batchResult{}, err
+	}
+
+	switch status {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		slog.Debug("Batch endpoint unavailable, applying changes one by one", "zone", zoneID, "status", status)
+		return applyIndividually(zoneID, auth, batch)
+	}
+	if status >= 400 {
+		return &batchResult{}, fmt.Errorf("POST %s failed with status: %d %s %v", path, status, http.StatusText(status), apiErrors(respBody))
+	}
+
+	var res struct {
+		Result batchResult `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &res); err != nil {
+		return &batchResult{}, fmt.Errorf("error parsing batch response: %v", err)
+	}
+	if len(res.Result.Deletes) != len(batch.Deletes) || len(res.Result.Puts) != len(batch.Puts) || len(res.Result.Posts) != len(batch.Posts) {
+		return &batchResult{}, fmt.Errorf("batch response does not match request")
+	}
+
+	recordChanges.WithLabelValues("deleted").Add(float64(len(batch.Deletes)))
+	recordChanges.WithLabelValues("updated").Add(float64(len(batch.Puts)))
+	recordChanges.WithLabelValues("created").Add(float64(len(batch.Posts)))
+	return &res.Result, nil
+}
+
+// applyIndividually makes the changes of a batch in the order Cloudflare
+// would, with one request each.
+func applyIndividually(zoneID string, auth cloudflareAuth, batch recordBatch) (*batchResult, error) {
+	result := &batchResult{}
+	for _, d := range batch.Deletes {
+		if err := deleteRecord(zoneID, d.ID, auth); err != nil {
+			return result, err
+		}
+		var deleted DNSRecord
+		deleted.ID = d.ID
+		result.Deletes = append(result.Deletes, deleted)
+	}
+	for _, p := range batch.Puts {
+		updated, err := updateRecord(zoneID, p.ID, auth, p.JSONRequest)
+		if err != nil {
+			return result, err
+		}
+		result.Puts = append(result.Puts, *updated)
+	}
+	for _, record := range batch.Posts {
+		created, err := createRecord(zoneID, auth, record)
+		if err != nil {
+			return result, err
+		}
+		result.Posts = append(result.Posts, *created)
+	}
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package resource
+
+import "testing"
+
+func tlsaRequest(name, certificate string) JSONRequest {
+	var req JSONRequest
+	req.Type = "TLSA"
+	req.Name = name
+	req.Ttl = defaultTTL
+	req.Data.Usage = 3
+	req.Data.Selector = 1
+	req.Data.Matchingtype = 1
+	req.Data.Certificate = certificate
+	return req
+}
+
+func TestApplyBatch(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	old := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", "")
+	kept := f.addRecord("zone-1", "_465._tcp.mail.example.com", 3, 1, 1, "bbbb", "")
+
+	batch := recordBatch{
+		Deletes: []batchDelete{{ID: old.ID}},
+		Puts:    []batchPut{{ID: kept.ID, JSONRequest: tlsaRequest("_465._tcp.mail", "cccc")}},
+		Posts:   []JSONRequest{tlsaRequest("_587._tcp.mail", "dddd")},
+	}
+	result, err := applyBatch("zone-1", cloudflareCredentials(), batch)
+	if err != nil {
+		t.Fatalf("applyBatch() error = %v", err)
+	}
+	if len(result.Deletes) != 1 || len(result.Puts) != 1 || len(result.Posts) != 1 {
+		t.Fatalf("Unexpected batch result: %+v", result)
+	}
+	if result.Puts[0].Data.Certificate != "cccc" || result.Posts[0].ID == "" {
+		t.Errorf("Unexpected records in batch result: %+v", result)
+	}
+	if n := f.countCalls("POST /zones/zone-1/dns_records/batch"); n != 1 {
+		t.Errorf("Expected 1 batch request, got %d", n)
+	}
+	if records := f.zoneRecords("zone-1"); len(records) != 2 {
+		t.Errorf("Expected 2 records after batch, got %d", len(records))
+	}
+}
+
+func TestApplyBatch_FailureChangesNothing(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	old := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", "")
+
+	batch := recordBatch{
+		Deletes: []batchDelete{{ID: old.ID}},
+		Puts:    []batchPut{{ID: "missing", JSONRequest: tlsaRequest("_465._tcp.mail", "cccc")}},
+	}
+	result, err := applyBatch("zone-1", cloudflareCredentials(), batch)
+	if err == nil {
+		t.Fatal("Expected error for unknown record in batch")
+	}
+	if result == nil || len(result.Deletes)+len(result.Puts)+len(result.Posts) != 0 {
+		t.Errorf("Expected empty result for a failed batch, got %+v", result)
+	}
+	if records := f.zoneRecords("zone-1"); len(records) != 1 || records[0].ID != old.ID {
+		t.Errorf("Failed batch changed records: %+v", records)
+	}
+}
+
+func TestApplyBatch_FallsBackToIndividualRequests(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.noBatch = true
+	old := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", "")
+
+	batch := recordBatch{
+		Deletes: []batchDelete{{ID: old.ID}},
+		Posts:   []JSONRequest{tlsaRequest("_25._tcp.mail", "bbbb")},
+	}
+	result, err := applyBatch("zone-1", cloudflareCredentials(), batch)
+	if err != nil {
+		t.Fatalf("applyBatch() error = %v", err)
+	}
+	if len(result.Deletes) != 1 || len(result.Posts) != 1 {
+		t.Errorf("Unexpected fallback result: %+v", result)
+	}
+	if f.countCalls("DELETE") != 1 || f.countCalls("POST /zones/zone-1/dns_records") != 2 {
+		t.Errorf("Expected individual requests after the batch was refused, got %v", f.calls)
+	}
+
+	// A failure stops the fallback and reports the changes already made
+	batch = recordBatch{
+		Deletes: []batchDelete{{ID: result.Posts[0].ID}, {ID: "missing"}},
+		Posts:   []JSONRequest{tlsaRequest("_25._tcp.mail", "cccc")},
+	}
+	result, err = applyBatch("zone-1", cloudflareCredentials(), batch)
+	if err == nil {
+		t.Fatal("Expected error for unknown record")
+	}
+	if len(result.Deletes) != 1 || len(result.Posts) != 0 {
+		t.Errorf("Expected only the first delete to be reported, got %+v", result)
+	}
+	if records := f.zoneRecords("zone-1"); len(records) != 0 {
+		t.Errorf("Expected the completed delete to stay applied, got %+v", records)
+	}
+}
@@ -0,0 +1,177 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Nagios/Icinga plugin exit codes returned by check.
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStatusNames = map[int]string{
+	checkOK:       "OK",
+	checkWarning:  "WARNING",
+	checkCritical: "CRITICAL",
+	checkUnknown:  "UNKNOWN",
+}
+
+// ExitError carries a specific process exit code out of a command.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// CheckError reports an error that stopped check before any record was
+// compared, such as an invalid flag or credential, as UNKNOWN. Errors that
+// already carry an exit code are returned as they are.
+func CheckError(err error) error {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return err
+	}
+	return reportCheck(os.Stdout, nil, err)
+}
+
+// checkResult is the outcome for one expected TLSA record.
+type checkResult struct {
+	Name     string
+	Usage    int
+	Expected string
+	Status   int
+	Detail   string
+}
+
+func ResourceCheck(cmd *cobra.Command, args []string) error {
+	all, err := loadTLSAOptions(cmd)
+	if err != nil {
+		return reportCheck(os.Stdout, nil, err)
+	}
+
+	results, err := checkRecords(all, cloudflareCredentials())
+	return reportCheck(os.Stdout, results, err)
+}
+
+// checkRecords compares the records the certificates call for with what the
+// provider publishes. A missing expected record is critical, whether or not
+// a stale record of the same usage is still published; extra stale records
+// next to the expected one are a warning.
+func checkRecords(all []tlsaOptions, auth cloudflareAuth) ([]checkResult, error) {
+	for _, opts := range all {
+		if _, _, err := opts.hashes(); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot, err := newProviderSnapshot(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []checkResult
+	for _, opts := range all {
+		zone, err := snapshot.zoneFor(opts.host())
+		if err != nil {
+			return nil, err
+		}
+
+		wanted, err := opts.records("Checked")
+		if err != nil {
+			return nil, err
+		}
+		for _, want := range wanted {
+			name := strings.ToLower(want.Name + "." + opts.URL)
+			existing, err := snapshot.recordsAt(zone.ID, name)
+			if err != nil {
+				return nil, err
+			}
+
+			result := checkResult{
+				Name:     name,
+				Usage:    want.Data.Usage,
+				Expected: formatTLSAData(want.Data.Usage, want.Data.Selector, want.Data.Matchingtype, want.Data.Certificate),
+			}
+
+			matched, stale := 0, 0
+			for _, have := range existing {
+				if have.Data.Usage != want.Data.Usage {
+					continue
+				}
+				if sameTLSAData(want, have) {
+					matched++
+				} else {
+					stale++
+				}
+			}
+
+			switch {
+			case matched > 0 && stale == 0:
+				result.Status = checkOK
+				result.Detail = "matches certificate"
+			case matched > 0:
+				result.Status = checkWarning
+				result.Detail = fmt.Sprintf("matches certificate, %d stale record(s) also published", stale)
+			case stale > 0:
+				result.Status = checkCritical
+				result.Detail = fmt.Sprintf("stale, %d record(s) published but none match certificate", stale)
+			default:
+				result.Status = checkCritical
+				result.Detail = "missing, no record published"
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// reportCheck prints the results in Nagios plugin format (status line first,
+// details after) and returns an ExitError for anything but OK.
+func reportCheck(w io.Writer, results []checkResult, err error) error {
+	if err != nil {
+		fmt.Fprintf(w, "TLSA %s - %s\n", checkStatusNames[checkUnknown], Redact(err.Error()))
+		return &ExitError{Code: checkUnknown, Err: err}
+	}
+
+	status := checkOK
+	var problems []string
+	for _, result := range results {
+		if result.Status > status {
+			status = result.Status
+		}
+		if result.Status != checkOK {
+			problems = append(problems, fmt.Sprintf("%s usage %d %s", result.Name, result.Usage, strings.SplitN(result.Detail, ",", 2)[0]))
+		}
+	}
+
+	summary := fmt.Sprintf("%d of %d TLSA record(s) match", len(results)-len(problems), len(results))
+	if len(problems) > 0 {
+		summary += ": " + strings.Join(problems, "; ")
+	}
+	fmt.Fprintf(w, "TLSA %s - %s\n", checkStatusNames[status], summary)
+
+	for _, result := range results {
+		fmt.Fprintf(w, "[%s] %s %s (expected %s)\n", checkStatusNames[result.Status], result.Name, result.Detail, result.Expected)
+	}
+
+	if status == checkOK {
+		return nil
+	}
+	return &ExitError{Code: status, Err: fmt.Errorf("TLSA %s", checkStatusNames[status])}
+}
@@ -0,0 +1,104 @@
+package resource
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckRecords(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = append(opts.Services, tlsaService{Port: "465", Protocol: "tcp"})
+	eeHash, _, err := opts.hashes()
+	if err != nil {
+		t.Fatalf("hashes() error = %v", err)
+	}
+
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, eeHash, "")
+	f.addRecord("zone-1", "_465._tcp.mail.example.com", 3, 1, 1, eeHash, "")
+	f.addRecord("zone-1", "_465._tcp.mail.example.com", 3, 1, 1, "0000", "")
+	f.addRecord("zone-1", "_587._tcp.mail.example.com", 3, 1, 1, "0000", "")
+
+	results, err := checkRecords([]tlsaOptions{opts}, cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("checkRecords() error = %v", err)
+	}
+
+	want := map[string]int{
+		"_25._tcp.mail.example.com":  checkOK,
+		"_465._tcp.mail.example.com": checkWarning,
+		"_587._tcp.mail.example.com": checkCritical,
+	}
+	for _, result := range results {
+		if result.Status != want[result.Name] {
+			t.Errorf("%s: expected status %d, got %d (%s)", result.Name, want[result.Name], result.Status, result.Detail)
+		}
+		if result.Name == "_587._tcp.mail.example.com" && !strings.HasPrefix(result.Detail, "stale") {
+			t.Errorf("Expected stale detail for port 587, got %s", result.Detail)
+		}
+	}
+}
+
+func TestCheckRecords_Missing(t *testing.T) {
+	newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+
+	results, err := checkRecords([]tlsaOptions{opts}, cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("checkRecords() error = %v", err)
+	}
+	for _, result := range results {
+		if result.Status != checkCritical || !strings.HasPrefix(result.Detail, "missing") {
+			t.Errorf("Expected missing/critical, got %d %s", result.Status, result.Detail)
+		}
+	}
+}
+
+func TestReportCheck(t *testing.T) {
+	testCases := []struct {
+		name     string
+		results  []checkResult
+		err      error
+		wantCode int
+		wantLine string
+	}{
+		{"OK", []checkResult{{Name: "a", Status: checkOK}}, nil, checkOK, "TLSA OK - 1 of 1"},
+		{"Warning", []checkResult{{Name: "a", Status: checkOK}, {Name: "b", Status: checkWarning, Detail: "matches certificate, 1 stale"}}, nil, checkWarning, "TLSA WARNING - 1 of 2"},
+		{"Critical", []checkResult{{Name: "a", Status: checkWarning}, {Name: "b", Status: checkCritical, Detail: "missing"}}, nil, checkCritical, "TLSA CRITICAL"},
+		{"Unknown", nil, errors.New("no matching zone"), checkUnknown, "TLSA UNKNOWN - no matching zone"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := reportCheck(&buf, tc.results, tc.err)
+
+			code := checkOK
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.Code
+			}
+			if code != tc.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tc.wantCode, code)
+			}
+			firstLine := strings.SplitN(buf.String(), "\n", 2)[0]
+			if !strings.HasPrefix(firstLine, tc.wantLine) {
+				t.Errorf("Expected status line %q, got %q", tc.wantLine, firstLine)
+			}
+		})
+	}
+}
+
+func TestCheckError(t *testing.T) {
+	var exitErr *ExitError
+	if err := CheckError(errors.New("invalid --log-level")); !errors.As(err, &exitErr) || exitErr.Code != checkUnknown {
+		t.Errorf("Expected UNKNOWN exit error, got %v", err)
+	}
+
+	critical := &ExitError{Code: checkCritical, Err: errors.New("TLSA CRITICAL")}
+	if err := CheckError(critical); err != critical {
+		t.Errorf("Expected exit error to be kept, got %v", err)
+	}
+}
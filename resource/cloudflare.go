@@ -0,0 +1,178 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// cloudflareAPI is the base URL of the Cloudflare v4 API. Tests point it at a
+// local httptest server.
+var cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// managedMarker is the comment fragment identifying records written by
+// gotlsaflare. Records without it are never modified or deleted by sync.
+const managedMarker = "by GoTLSAFlare"
+
+func isManaged(record DNSRecord) bool {
+	return strings.Contains(record.Comment, managedMarker)
+}
+
+// cloudflareDo performs an API call and returns the response body, turning
+// HTTP and API level failures into errors.
+func cloudflareDo(method, path string, auth cloudflareAuth, body []byte) ([]byte, error) {
+	status, respBody, err := cloudflareRequest(method, path, auth, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if status >= 400 {
+		return nil, fmt.Errorf("%s %s failed with status: %d %s %v", method, path, status, http.StatusText(status), apiErrors(respBody))
+	}
+
+	return respBody, nil
+}
+
+// cloudflareRequest performs an API call and returns the status code and
+// body without interpreting them.
+func cloudflareRequest(method, path string, auth cloudflareAuth, body []byte) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewBuffer(body)
+	}
+
+	req, err := http.NewRequest(method, cloudflareAPI+path, reader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	auth.apply(req)
+
+	client := cloudflareClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error on %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error reading response: %v", err)
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// apiErrors returns the errors array of an API response.
+func apiErrors(body []byte) []interface{} {
+	var res struct {
+		Errors []interface{} `json:"errors"`
+	}
+	json.Unmarshal(body, &res)
+	return res.Errors
+}
+
+// listZones returns every zone visible to the token, following pagination.
+func listZones(auth cloudflareAuth) ([]Zone, error) {
+	var zones []Zone
+	for page := 1; ; page++ {
+		body, err := cloudflareDo("GET", fmt.Sprintf("/zones?per_page=50&page=%d", page), auth, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var res Res
+		if err := json.Unmarshal(body, &res); err != nil {
+			return nil, fmt.Errorf("error parsing zone response: %v", err)
+		}
+		zones = append(zones, res.Result...)
+
+		if page >= res.ResultInfo.TotalPages {
+			return zones, nil
+		}
+	}
+}
+
+// findZone returns the zone that name belongs to, preferring the longest
+// matching zone so delegated child zones win over their parent.
+func findZone(zones []Zone, name string) (Zone, bool) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	var best Zone
+	found := false
+	for _, zone := range zones {
+		zoneName := strings.ToLower(zone.Name)
+		if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+			continue
+		}
+		if !found || len(zoneName) > len(best.Name) {
+			best = zone
+			found = true
+		}
+	}
+	return best, found
+}
+
+// listTLSARecords returns all TLSA records in a zone, following pagination.
+func listTLSARecords(zoneID string, auth cloudflareAuth) ([]DNSRecord, error) {
+	return listRecordsOfType(zoneID, "TLSA", auth)
+}
+
+// listRecordsOfType returns all records of one type in a zone, following
+// pagination.
+func listRecordsOfType(zoneID, recordType string, auth cloudflareAuth) ([]DNSRecord, error) {
+	var records []DNSRecord
+	for page := 1; ; page++ {
+		body, err := cloudflareDo("GET", fmt.Sprintf("/zones/%s/dns_records?type=%s&per_page=100&page=%d", zoneID, recordType, page), auth, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var res RecordsRes
+		if err := json.Unmarshal(body, &res); err != nil {
+			return nil, fmt.Errorf("error parsing records response: %v", err)
+		}
+		for _, record := range res.Result {
+			if record.Type == recordType {
+				records = append(records, record)
+			}
+		}
+
+		if page >= res.ResultInfo.TotalPages {
+			return records, nil
+		}
+	}
+}
+
+func createRecord(zoneID string, auth cloudflareAuth, record JSONRequest) (*DNSRecord, error) {
+	return writeRecord("POST", "/zones/"+zoneID+"/dns_records", auth, record)
+}
+
+func updateRecord(zoneID, recordID string, auth cloudflareAuth, record JSONRequest) (*DNSRecord, error) {
+	return writeRecord("PUT", "/zones/"+zoneID+"/dns_records/"+recordID, auth, record)
+}
+
+func writeRecord(method, path string, auth cloudflareAuth, record any) (*DNSRecord, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding record: %v", err)
+	}
+
+	respBody, err := cloudflareDo(method, path, auth, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Result DNSRecord `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &res); err != nil {
+		return nil, fmt.Errorf("error parsing record response: %v", err)
+	}
+	return &res.Result, nil
+}
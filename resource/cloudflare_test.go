@@ -0,0 +1,384 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeCloudflare is an in-memory stand-in for the parts of the Cloudflare
+// API used by gotlsaflare.
+type fakeCloudflare struct {
+	mu      sync.Mutex
+	zones   []Zone
+	records map[string][]DNSRecord // by zone ID
+	nextID  int
+	calls   []string
+	perPage int
+
+	// readOnly makes every write fail as it would for a token without
+	// Zone:DNS:Edit. dnssec holds the DNSSEC status by zone ID.
+	readOnly bool
+	dnssec   map[string]string
+
+	// noBatch answers the batch endpoint with 404, as for an account that
+	// cannot use it.
+	noBatch bool
+
+	// probeStatus, when set, answers the empty write probe of preflight with
+	// that status instead of 400.
+	probeStatus int
+
+	// onWrite, when set, is called before every request that is not a GET.
+	onWrite func()
+}
+
+func newFakeCloudflare(t *testing.T, zoneNames ...string) *fakeCloudflare {
+	t.Helper()
+
+	f := &fakeCloudflare{records: make(map[string][]DNSRecord), dnssec: make(map[string]string)}
+	for i, name := range zoneNames {
+		var zone Zone
+		zone.ID = fmt.Sprintf("zone-%d", i+1)
+		zone.Name = name
+		f.zones = append(f.zones, zone)
+	}
+
+	server := httptest.NewServer(f)
+	original := cloudflareAPI
+	cloudflareAPI = server.URL
+	t.Setenv("TOKEN", "test-token")
+	t.Cleanup(func() {
+		cloudflareAPI = original
+		server.Close()
+	})
+	return f
+}
+
+func (f *fakeCloudflare) addRecord(zoneID, name string, usage, selector, matchingType int, certificate, comment string) DNSRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var record DNSRecord
+	f.nextID++
+	record.ID = fmt.Sprintf("record-%d", f.nextID)
+	record.ZoneID = zoneID
+	record.Name = name
+	record.Type = "TLSA"
+	record.TTL = 3600
+	record.Data.Usage = usage
+	record.Data.Selector = selector
+	record.Data.MatchingType = matchingType
+	record.Data.Certificate = certificate
+	record.Comment = comment
+	f.records[zoneID] = append(f.records[zoneID], record)
+	return record
+}
+
+func (f *fakeCloudflare) zoneRecords(zoneID string) []DNSRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]DNSRecord(nil), f.records[zoneID]...)
+}
+
+func (f *fakeCloudflare) countCalls(prefix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, call := range f.calls {
+		if strings.HasPrefix(call, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeCloudflare) zoneName(zoneID string) string {
+	for _, zone := range f.zones {
+		if zone.ID == zoneID {
+			return zone.Name
+		}
+	}
+	return ""
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	if f.onWrite != nil && r.Method != "GET" {
+		f.onWrite()
+	}
+
+	globalKey := r.Header.Get("X-Auth-Email") == "hostmaster@example.com" && r.Header.Get("X-Auth-Key") == "test-key"
+	if r.Header.Get("Authorization") != "Bearer test-token" && !globalKey {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []string{"unauthorized"}})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if f.readOnly && r.Method != "GET" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"code": 10000, "message": "Authentication error"}}})
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/user/tokens/verify" && !globalKey:
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": map[string]string{"id": "token-1", "status": "active"}})
+
+	case r.URL.Path == "/user" && globalKey:
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": map[string]string{"email": "hostmaster@example.com"}})
+
+	case len(parts) == 3 && parts[2] == "dnssec" && r.Method == "GET":
+		status := f.dnssec[parts[1]]
+		if status == "" {
+			status = "active"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": map[string]string{"status": status}})
+
+	case len(parts) == 1 && parts[0] == "zones":
+		var res Res
+		res.Success = true
+		res.Result = f.zones
+		res.ResultInfo.Page = 1
+		res.ResultInfo.TotalPages = 1
+		json.NewEncoder(w).Encode(res)
+
+	case len(parts) == 3 && parts[2] == "dns_records" && r.Method == "GET":
+		records := f.records[parts[1]]
+		if t := r.URL.Query().Get("type"); t != "" {
+			var filtered []DNSRecord
+			for _, record := range records {
+				if record.Type == t {
+					filtered = append(filtered, record)
+				}
+			}
+			records = filtered
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		perPage := f.perPage
+		if perPage == 0 {
+			perPage = len(records) + 1
+		}
+		totalPages := (len(records) + perPage - 1) / perPage
+		start := (page - 1) * perPage
+		end := start + perPage
+		if start > len(records) {
+			start = len(records)
+		}
+		if end > len(records) {
+			end = len(records)
+		}
+
+		var res RecordsRes
+		res.Success = true
+		res.Result = records[start:end]
+		res.ResultInfo.Page = page
+		res.ResultInfo.TotalPages = totalPages
+		json.NewEncoder(w).Encode(res)
+
+	case len(parts) == 3 && parts[2] == "dns_records" && r.Method == "POST":
+		var req fakeRecordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Type == "" {
+			if f.probeStatus != 0 {
+				w.WriteHeader(f.probeStatus)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"code": 9000, "message": "DNS name is invalid."}}})
+			return
+		}
+		record := f.fromRequest(parts[1], req.JSONRequest)
+		record.Content = req.Content
+		f.nextID++
+		record.ID = fmt.Sprintf("record-%d", f.nextID)
+		f.records[parts[1]] = append(f.records[parts[1]], record)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": record})
+
+	case len(parts) == 4 && parts[2] == "dns_records" && parts[3] == "batch" && r.Method == "POST":
+		if f.noBatch {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var batch recordBatch
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		result, ok := f.applyBatch(parts[1], batch)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"code": 81044, "message": "Record does not exist."}}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": result})
+
+	case len(parts) == 4 && parts[2] == "dns_records" && r.Method == "PUT":
+		var req fakeRecordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for i, record := range f.records[parts[1]] {
+			if record.ID == parts[3] {
+				updated := f.fromRequest(parts[1], req.JSONRequest)
+				updated.Content = req.Content
+				updated.ID = record.ID
+				f.records[parts[1]][i] = updated
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": updated})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+
+	case len(parts) == 4 && parts[2] == "dns_records" && r.Method == "DELETE":
+		records := f.records[parts[1]]
+		for i, record := range records {
+			if record.ID == parts[3] {
+				f.records[parts[1]] = append(records[:i:i], records[i+1:]...)
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": map[string]string{"id": record.ID}})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// applyBatch applies the batch to a copy of the zone and keeps it only if
+// every change succeeds, like the real endpoint.
+func (f *fakeCloudflare) applyBatch(zoneID string, batch recordBatch) (batchResult, bool) {
+	records := slices.Clone(f.records[zoneID])
+	nextID := f.nextID
+	var result batchResult
+
+	for _, d := range batch.Deletes {
+		i := slices.IndexFunc(records, func(r DNSRecord) bool { return r.ID == d.ID })
+		if i < 0 {
+			return batchResult{}, false
+		}
+		result.Deletes = append(result.Deletes, records[i])
+		records = slices.Delete(records, i, i+1)
+	}
+	for _, p := range batch.Puts {
+		i := slices.IndexFunc(records, func(r DNSRecord) bool { return r.ID == p.ID })
+		if i < 0 {
+			return batchResult{}, false
+		}
+		updated := f.fromRequest(zoneID, p.JSONRequest)
+		updated.ID = p.ID
+		records[i] = updated
+		result.Puts = append(result.Puts, updated)
+	}
+	for _, req := range batch.Posts {
+		if req.Type == "" {
+			return batchResult{}, false
+		}
+		record := f.fromRequest(zoneID, req)
+		nextID++
+		record.ID = fmt.Sprintf("record-%d", nextID)
+		records = append(records, record)
+		result.Posts = append(result.Posts, record)
+	}
+
+	f.records[zoneID] = records
+	f.nextID = nextID
+	return result, true
+}
+
+// fakeRecordRequest is the body of a single record write, which carries content
+// for record types other than TLSA.
+type fakeRecordRequest struct {
+	JSONRequest
+	Content string `json:"content"`
+}
+
+// fromRequest converts a request body into the record Cloudflare would store,
+// expanding the relative name with the zone name.
+func (f *fakeCloudflare) fromRequest(zoneID string, req JSONRequest) DNSRecord {
+	var record DNSRecord
+	record.ZoneID = zoneID
+	record.Name = req.Name
+	if zone := f.zoneName(zoneID); !strings.HasSuffix(record.Name, zone) {
+		record.Name += "." + zone
+	}
+	record.Type = req.Type
+	record.TTL = req.Ttl
+	record.Data.Usage = req.Data.Usage
+	record.Data.Selector = req.Data.Selector
+	record.Data.MatchingType = req.Data.Matchingtype
+	record.Data.Certificate = req.Data.Certificate
+	record.Comment = req.Comment
+	record.Tags = req.Tags
+	return record
+}
+
+func TestFindZone(t *testing.T) {
+	var parent, child Zone
+	parent.ID, parent.Name = "zone-1", "example.com"
+	child.ID, child.Name = "zone-2", "sub.example.com"
+	zones := []Zone{parent, child}
+
+	testCases := []struct {
+		name   string
+		wantID string
+		found  bool
+	}{
+		{"_25._tcp.mail.example.com", "zone-1", true},
+		{"_25._tcp.mail.sub.example.com", "zone-2", true},
+		{"example.com", "zone-1", true},
+		{"mail.notexample.com", "", false},
+	}
+
+	for _, tc := range testCases {
+		zone, ok := findZone(zones, tc.name)
+		if ok != tc.found || zone.ID != tc.wantID {
+			t.Errorf("findZone(%s) = %s, %v; want %s, %v", tc.name, zone.ID, ok, tc.wantID, tc.found)
+		}
+	}
+}
+
+func TestListTLSARecords_Pagination(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.perPage = 2
+	for i := 0; i < 5; i++ {
+		f.addRecord("zone-1", fmt.Sprintf("_%d._tcp.mail.example.com", 25+i), 3, 1, 1, "abcd", "")
+	}
+
+	records, err := listTLSARecords("zone-1", cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("listTLSARecords() error = %v", err)
+	}
+	if len(records) != 5 {
+		t.Errorf("Expected 5 records across pages, got %d", len(records))
+	}
+	if n := f.countCalls("GET /zones/zone-1/dns_records"); n != 3 {
+		t.Errorf("Expected 3 page requests, got %d", n)
+	}
+}
+
+func TestCloudflareDo_ErrorStatus(t *testing.T) {
+	newFakeCloudflare(t, "example.com")
+	t.Setenv("TOKEN", "wrong-token")
+
+	_, err := listZones(cloudflareCredentials())
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected 403 error, got %v", err)
+	}
+}
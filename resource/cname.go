@@ -0,0 +1,117 @@
+package resource
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+)
+
+// What --cname does with a host that is a CNAME.
+const (
+	cnameOff    = "off"
+	cnameWarn   = "warn"
+	cnameTarget = "target"
+	cnameBoth   = "both"
+)
+
+// maxCNAMEs bounds the chains cnameChain follows.
+const maxCNAMEs = 8
+
+// followCNAMEs checks whether the host of each set of options is a CNAME.
+// Following RFC 7671, clients then look up the TLSA records at the end of the
+// chain first, and at the host name only when the target has none. --cname
+// off, the default, sends no lookups; warn only explains this; target moves
+// the records to the CNAME target, and both publishes at the host name as
+// well. Targets outside the zones that can be managed keep the records at the
+// host name. Commands without --cname, and hosts whose CNAMEs cannot be
+// resolved, are left as they are.
+func followCNAMEs(cmd *cobra.Command, all []tlsaOptions) ([]tlsaOptions, error) {
+	f := cmd.Flags().Lookup("cname")
+	if f == nil {
+		return all, nil
+	}
+	mode := f.Value.String()
+	if mode == cnameOff {
+		return all, nil
+	}
+	if mode != cnameWarn && mode != cnameTarget && mode != cnameBoth {
+		return nil, fmt.Errorf("invalid --cname %q, must be one of off, warn, target, both", mode)
+	}
+	resolver, _ := cmd.Flags().GetString("resolver")
+	if resolver == "" {
+		resolver = systemResolver()
+	}
+
+	var zones []Zone
+	var followed []tlsaOptions
+	for _, opts := range all {
+		host := opts.host()
+		chain, err := cnameChain(resolver, host)
+		if err != nil {
+			slog.Debug("Could not check whether host is a CNAME", "host", host, "error", err)
+		}
+		if len(chain) == 0 {
+			followed = append(followed, opts)
+			continue
+		}
+
+		target := chain[len(chain)-1]
+		slog.Warn("Host is a CNAME, clients look up TLSA records at the CNAME target first and at the host name only when the target has none",
+			"host", host, "target", target, "chain", strings.Join(chain, " -> "))
+		if mode == cnameWarn {
+			followed = append(followed, opts)
+			continue
+		}
+
+		if zones == nil {
+			if zones, err = providerFor(cloudflareCredentials()).zones(); err != nil {
+				return nil, err
+			}
+		}
+		zone, ok := findZone(zones, target)
+		if !ok {
+			slog.Warn("CNAME target is outside the zones that can be managed, publishing at the host name only", "host", host, "target", target)
+			followed = append(followed, opts)
+			continue
+		}
+		if mode == cnameBoth {
+			followed = append(followed, opts)
+		}
+		slog.Info("Publishing TLSA records at the CNAME target", "host", host, "target", target)
+		followed = append(followed, optionsAt(opts, target, zone))
+	}
+	return followed, nil
+}
+
+// cnameChain returns the names the CNAMEs of host lead to, in order, or
+// nothing when host is not a CNAME.
+func cnameChain(resolver, host string) ([]string, error) {
+	var chain []string
+	name := host
+	for range maxCNAMEs {
+		answers, err := lookupRecords(resolver, name, dns.TypeCNAME)
+		if err != nil {
+			return nil, err
+		}
+
+		next := ""
+		for _, rr := range answers {
+			if cname, ok := rr.(*dns.CNAME); ok && targetHost(cname.Hdr.Name) == name {
+				next = targetHost(cname.Target)
+			}
+		}
+		if next == "" {
+			return chain, nil
+		}
+		if next == host || slices.Contains(chain, next) {
+			return nil, fmt.Errorf("CNAME loop at %s", next)
+		}
+		chain = append(chain, next)
+		name = next
+	}
+	return nil, fmt.Errorf("more than %d CNAMEs from %s", maxCNAMEs, host)
+}
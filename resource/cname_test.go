@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCnameChain(t *testing.T) {
+	resolver := newFakeResolver(t,
+		"mx.example.com. 300 IN CNAME relay.example.org.",
+		"relay.example.org. 300 IN CNAME Out.Provider.Net.",
+		"loop1.example.com. 300 IN CNAME loop2.example.com.",
+		"loop2.example.com. 300 IN CNAME loop1.example.com.",
+	)
+
+	chain, err := cnameChain(resolver, "mx.example.com")
+	if err != nil || strings.Join(chain, ",") != "relay.example.org,out.provider.net" {
+		t.Errorf("cnameChain() = %v, %v", chain, err)
+	}
+	if chain, err := cnameChain(resolver, "mail.example.com"); err != nil || len(chain) != 0 {
+		t.Errorf("Expected no chain for a plain host, got %v, %v", chain, err)
+	}
+	if _, err := cnameChain(resolver, "loop1.example.com"); err == nil || !strings.Contains(err.Error(), "CNAME loop") {
+		t.Errorf("Expected loop error, got %v", err)
+	}
+}
+
+func TestFollowCNAMEs(t *testing.T) {
+	newFakeCloudflare(t, "example.com", "example.org")
+	resolver := newFakeResolver(t,
+		"mx1.example.com. 300 IN CNAME smtp.example.org.",
+		"mx2.example.com. 300 IN CNAME mx.provider.net.",
+	)
+	all := []tlsaOptions{
+		{URL: "example.com", Subdomain: "mx1"},
+		{URL: "example.com", Subdomain: "mx2"},
+		{URL: "example.com", Subdomain: "mail"},
+	}
+
+	testCases := []struct {
+		mode string
+		want string
+	}{
+		{cnameOff, "mx1.example.com,mx2.example.com,mail.example.com"},
+		{cnameWarn, "mx1.example.com,mx2.example.com,mail.example.com"},
+		// mx.provider.net cannot be managed, so mx2 keeps its records
+		{cnameTarget, "smtp.example.org,mx2.example.com,mail.example.com"},
+		{cnameBoth, "mx1.example.com,smtp.example.org,mx2.example.com,mail.example.com"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String("cname", tc.mode, "")
+			cmd.Flags().String("resolver", resolver, "")
+
+			followed, err := followCNAMEs(cmd, all)
+			if err != nil {
+				t.Fatalf("followCNAMEs() error = %v", err)
+			}
+			var hosts []string
+			for _, opts := range followed {
+				hosts = append(hosts, opts.host())
+			}
+			if strings.Join(hosts, ",") != tc.want {
+				t.Errorf("Expected hosts %s, got %s", tc.want, strings.Join(hosts, ","))
+			}
+		})
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("cname", "follow", "")
+	if _, err := followCNAMEs(cmd, all); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,109 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultCommentTemplate reproduces the comment written before --comment
+// existed, e.g. "Created by GoTLSAFlare - 2024-05-01 12:00:00".
+const defaultCommentTemplate = "{{.Action}} " + managedMarker + " - {{.Time}}"
+
+// commentData holds the fields available to --comment templates.
+type commentData struct {
+	Action   string // Created, Updated, ...
+	Name     string // owner name of the record, e.g. _25._tcp.mail.example.com
+	Usage    int
+	Time     commentTime
+	Serial   string // serial number of the certificate in hex
+	Subject  string // common name of the certificate
+	NotAfter commentTime
+}
+
+// commentTime prints as "2006-01-02 15:04:05" in templates while keeping
+// the time.Time methods, so {{.NotAfter.Format "2006-01-02"}} also works.
+type commentTime struct {
+	time.Time
+}
+
+func (t commentTime) String() string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func parseCommentTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultCommentTemplate
+	}
+	tmpl, err := template.New("comment").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment template: %v", err)
+	}
+	// Catch unknown fields now rather than halfway through a run
+	if err := tmpl.Execute(&bytes.Buffer{}, commentData{}); err != nil {
+		return nil, fmt.Errorf("invalid comment template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// comment renders the comment template for one record. The result always
+// contains managedMarker so later runs recognise the record.
+func (o tlsaOptions) comment(action, name string, usage int) string {
+	tmpl, err := parseCommentTemplate(o.Comment)
+	if err != nil {
+		slog.Warn("Using default comment", "error", err)
+		return recordComment(action)
+	}
+
+	data := commentData{Action: action, Name: name, Usage: usage, Time: commentTime{time.Now()}}
+	if cert, err := leafCertificate(o.Cert); err == nil {
+		data.Serial = cert.SerialNumber.Text(16)
+		data.Subject = cert.Subject.CommonName
+		data.NotAfter = commentTime{cert.NotAfter}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		slog.Warn("Using default comment", "error", err)
+		return recordComment(action)
+	}
+
+	comment := strings.TrimSpace(buf.String())
+	if !strings.Contains(comment, managedMarker) {
+		comment += " " + managedMarker
+	}
+	return comment
+}
+
+// leafCertificate returns the first certificate in a PEM file.
+func leafCertificate(certfile string) (*x509.Certificate, error) {
+	pemContent, err := os.ReadFile(certfile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(pemContent)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse pem file %s", certfile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate in %s: %v", certfile, err)
+	}
+	return cert, nil
+}
+
+// validateTag checks a Cloudflare record tag, written as name:value or
+// just name.
+func validateTag(tag string) error {
+	name, _, _ := strings.Cut(tag, ":")
+	if name == "" || strings.ContainsAny(tag, " \t\n") {
+		return fmt.Errorf("invalid tag %q, must be name:value without spaces", tag)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package resource
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTLSAOptions_Comment(t *testing.T) {
+	opts := syncTestOptions(t)
+	cert, err := leafCertificate(opts.Cert)
+	if err != nil {
+		t.Fatalf("leafCertificate() error = %v", err)
+	}
+
+	// The default keeps the comment format of earlier releases
+	got := opts.comment("Created", "_25._tcp.mail.example.com", 3)
+	if !regexp.MustCompile(`^Created by GoTLSAFlare - \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}$`).MatchString(got) {
+		t.Errorf("Unexpected default comment: %q", got)
+	}
+
+	opts.Comment = `CHG-1234 {{.Action}} {{.Name}} usage {{.Usage}} serial {{.Serial}} {{.Subject}} expires {{.NotAfter.Format "2006-01-02"}} by GoTLSAFlare`
+	got = opts.comment("Updated", "_25._tcp.mail.example.com", 3)
+	want := "CHG-1234 Updated _25._tcp.mail.example.com usage 3 serial " + cert.SerialNumber.Text(16) +
+		" test.example.com expires " + cert.NotAfter.Format("2006-01-02") + " by GoTLSAFlare"
+	if got != want {
+		t.Errorf("Expected comment %q, got %q", want, got)
+	}
+
+	// A template without the marker still produces a managed comment
+	opts.Comment = "CHG-1234 {{.Action}}"
+	if got := opts.comment("Created", "", 3); got != "CHG-1234 Created "+managedMarker {
+		t.Errorf("Expected marker to be appended, got %q", got)
+	}
+}
+
+func TestParseCommentTemplate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"Default", "", ""},
+		{"Fields", "{{.Action}} {{.Time}} {{.Serial}} {{.NotAfter}}", ""},
+		{"Syntax", "{{.Action", "invalid comment template"},
+		{"UnknownField", "{{.Ticket}}", "Ticket"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseCommentTemplate(tc.text)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("parseCommentTemplate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	for _, tag := range []string{"team:mail", "dane", "ticket:CHG-1234"} {
+		if err := validateTag(tag); err != nil {
+			t.Errorf("validateTag(%q) error = %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", ":mail", "team:mail server"} {
+		if err := validateTag(tag); err == nil {
+			t.Errorf("Expected validateTag(%q) to fail", tag)
+		}
+	}
+}
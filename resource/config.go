@@ -0,0 +1,259 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative description of every TLSA record managed by
+// gotlsaflare, loaded with --config in place of the per-invocation flags.
+//
+//	defaults:
+//	  zone: example.com
+//	  cert: /etc/letsencrypt/live/mail.example.com/fullchain.pem
+//	  usages: [dane-ee, dane-ta]
+//	  ttl: 3600
+//	  comment: "{{.Action}} by GoTLSAFlare, CHG-1234"
+//	  tags: [team:mail]
+//	records:
+//	  - hostname: mail
+//	    services:
+//	      - port: 25
+//	      - port: 465
+//	        proto: tcp
+//
+// Any field left out of a record is taken from defaults.
+type Config struct {
+	Defaults ConfigRecord   `yaml:"defaults"`
+	Records  []ConfigRecord `yaml:"records"`
+}
+
+type ConfigRecord struct {
+	Zone         string          `yaml:"zone"`
+	Hostname     string          `yaml:"hostname"`
+	Cert         string          `yaml:"cert"`
+	Services     []ConfigService `yaml:"services"`
+	Usages       []string        `yaml:"usages"`
+	Selector     *int            `yaml:"selector"`
+	MatchingType *int            `yaml:"matching_type"`
+	TTL          *configTTL      `yaml:"ttl"`
+	Comment      string          `yaml:"comment"`
+	Tags         []string        `yaml:"tags"`
+}
+
+// configTTL is a TTL in seconds, or "auto" for Cloudflare's automatic TTL.
+type configTTL int
+
+func (t *configTTL) UnmarshalYAML(value *yaml.Node) error {
+	ttl, err := parseTTL(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %v", value.Line, err)
+	}
+	*t = configTTL(ttl)
+	return nil
+}
+
+type ConfigService struct {
+	Port  int    `yaml:"port"`
+	Proto string `yaml:"proto"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %v", err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %v", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Records) == 0 {
+		return fmt.Errorf("records: at least one record is required")
+	}
+
+	var problems []string
+	for i, record := range c.Records {
+		for _, problem := range record.merge(c.Defaults).problems() {
+			problems = append(problems, fmt.Sprintf("records[%d]: %s", i, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// merge fills the fields left empty in the record from defaults.
+func (r ConfigRecord) merge(defaults ConfigRecord) ConfigRecord {
+	if r.Zone == "" {
+		r.Zone = defaults.Zone
+	}
+	if r.Hostname == "" {
+		r.Hostname = defaults.Hostname
+	}
+	if r.Cert == "" {
+		r.Cert = defaults.Cert
+	}
+	if len(r.Services) == 0 {
+		r.Services = defaults.Services
+	}
+	if len(r.Usages) == 0 {
+		r.Usages = defaults.Usages
+	}
+	if r.Selector == nil {
+		r.Selector = defaults.Selector
+	}
+	if r.MatchingType == nil {
+		r.MatchingType = defaults.MatchingType
+	}
+	if r.TTL == nil {
+		r.TTL = defaults.TTL
+	}
+	if r.Comment == "" {
+		r.Comment = defaults.Comment
+	}
+	if len(r.Tags) == 0 {
+		r.Tags = defaults.Tags
+	}
+	return r
+}
+
+func (r ConfigRecord) problems() []string {
+	var problems []string
+
+	if r.Zone == "" {
+		problems = append(problems, "zone is required")
+	}
+	if r.Hostname == "" {
+		problems = append(problems, "hostname is required, use \"@\" for the zone apex")
+	} else if err := validateSubdomain(r.Hostname); err != nil {
+		problems = append(problems, fmt.Sprintf("hostname: %v", err))
+	}
+	if r.Cert == "" {
+		problems = append(problems, "cert is required")
+	}
+	if len(r.Services) == 0 {
+		problems = append(problems, "services: at least one service is required")
+	}
+	for i, svc := range r.Services {
+		if svc.Port < 1 || svc.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("services[%d].port: %d is not between 1 and 65535", i, svc.Port))
+		}
+		switch svc.Proto {
+		case "", "tcp", "udp", "sctp":
+		default:
+			problems = append(problems, fmt.Sprintf("services[%d].proto: %q must be one of tcp, udp, sctp", i, svc.Proto))
+		}
+	}
+	for i, usage := range r.Usages {
+		if _, err := parseUsage(usage); err != nil {
+			problems = append(problems, fmt.Sprintf("usages[%d]: %v", i, err))
+		}
+	}
+	if r.Selector != nil && *r.Selector != 0 && *r.Selector != 1 {
+		problems = append(problems, fmt.Sprintf("selector: %d must be 0 (Full cert) or 1 (SubjectPublicKeyInfo)", *r.Selector))
+	}
+	if r.MatchingType != nil && *r.MatchingType != 1 && *r.MatchingType != 2 {
+		problems = append(problems, fmt.Sprintf("matching_type: %d must be 1 (SHA2-256) or 2 (SHA2-512)", *r.MatchingType))
+	}
+	if r.TTL != nil && *r.TTL != ttlAuto && (*r.TTL < 60 || *r.TTL > 86400) {
+		problems = append(problems, fmt.Sprintf("ttl: %d must be auto (1) or between 60 and 86400", *r.TTL))
+	}
+	if _, err := parseCommentTemplate(r.Comment); err != nil {
+		problems = append(problems, fmt.Sprintf("comment: %v", err))
+	}
+	for i, tag := range r.Tags {
+		if err := validateTag(tag); err != nil {
+			problems = append(problems, fmt.Sprintf("tags[%d]: %v", i, err))
+		}
+	}
+
+	return problems
+}
+
+// parseUsage accepts the usage names used by the command line flags as well
+// as the numeric TLSA certificate usage.
+func parseUsage(usage string) (int, error) {
+	switch strings.ToLower(usage) {
+	case "dane-ee", "3":
+		return 3, nil
+	case "dane-ta", "2":
+		return 2, nil
+	}
+	return 0, fmt.Errorf("%q must be one of dane-ee (3), dane-ta (2)", usage)
+}
+
+// options converts the config into one tlsaOptions per record.
+func (c *Config) options() ([]tlsaOptions, error) {
+	var all []tlsaOptions
+	for _, record := range c.Records {
+		r := record.merge(c.Defaults)
+
+		opts := tlsaOptions{
+			URL:          r.Zone,
+			Subdomain:    r.Hostname,
+			Cert:         r.Cert,
+			Selector:     -1,
+			MatchingType: 1,
+			Comment:      r.Comment,
+			Tags:         r.Tags,
+		}
+		if r.Selector != nil {
+			opts.Selector = *r.Selector
+		}
+		if r.MatchingType != nil {
+			opts.MatchingType = *r.MatchingType
+		}
+		if r.TTL != nil {
+			opts.TTL = int(*r.TTL)
+		}
+
+		for _, svc := range r.Services {
+			proto := svc.Proto
+			if proto == "" {
+				proto = "tcp"
+			}
+			opts.Services = append(opts.Services, tlsaService{Port: strconv.Itoa(svc.Port), Protocol: proto})
+		}
+
+		if len(r.Usages) == 0 {
+			opts.DaneEE = true
+		}
+		for _, usage := range r.Usages {
+			u, err := parseUsage(usage)
+			if err != nil {
+				return nil, err
+			}
+			if u == 3 {
+				opts.DaneEE = true
+			} else {
+				opts.DaneTA = true
+			}
+		}
+
+		if err := opts.validate(); err != nil {
+			return nil, err
+		}
+		warnCertCoverage(opts)
+		all = append(all, opts)
+	}
+	return all, nil
+}
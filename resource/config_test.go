@@ -0,0 +1,218 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "gotlsaflare.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_Valid(t *testing.T) {
+	path := writeTestConfig(t, `
+defaults:
+  zone: example.com
+  cert: /etc/ssl/fullchain.pem
+  usages: [dane-ee, dane-ta]
+  ttl: 300
+records:
+  - hostname: mail
+    services:
+      - port: 25
+      - port: 465
+        proto: tcp
+  - zone: example.org
+    hostname: xmpp
+    cert: /etc/ssl/xmpp.pem
+    usages: ["3"]
+    selector: 0
+    matching_type: 2
+    services:
+      - port: 5269
+`)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+
+	all, err := config.options()
+	if err != nil {
+		t.Fatalf("options() error = %v", err)
+	}
+
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(all))
+	}
+
+	mail := all[0]
+	if mail.URL != "example.com" || mail.Subdomain != "mail" || mail.Cert != "/etc/ssl/fullchain.pem" {
+		t.Errorf("Defaults not applied to first record: %+v", mail)
+	}
+	if !mail.DaneEE || !mail.DaneTA {
+		t.Errorf("Expected DANE-EE and DANE-TA, got %+v", mail)
+	}
+	if mail.TTL != 300 || mail.Selector != -1 || mail.MatchingType != 1 {
+		t.Errorf("Unexpected TTL/selector/matching type: %+v", mail)
+	}
+	if len(mail.Services) != 2 || mail.Services[1].prefix() != "_465._tcp." {
+		t.Errorf("Unexpected services: %+v", mail.Services)
+	}
+
+	xmpp := all[1]
+	if xmpp.URL != "example.org" || !xmpp.DaneEE || xmpp.DaneTA {
+		t.Errorf("Unexpected second record: %+v", xmpp)
+	}
+	if xmpp.Selector != 0 || xmpp.MatchingType != 2 {
+		t.Errorf("Expected selector 0 and matching type 2, got %d and %d", xmpp.Selector, xmpp.MatchingType)
+	}
+}
+
+func TestLoadConfig_UnknownField(t *testing.T) {
+	path := writeTestConfig(t, `
+records:
+  - zone: example.com
+    hostname: mail
+    cert: cert.pem
+    servises:
+      - port: 25
+`)
+
+	_, err := loadConfig(path)
+	if err == nil {
+		t.Fatal("Expected error for unknown field")
+	}
+	if !strings.Contains(err.Error(), "servises") || !strings.Contains(err.Error(), "line 6") {
+		t.Errorf("Expected error to name the field and line, got: %v", err)
+	}
+}
+
+func TestLoadConfig_ValidationErrors(t *testing.T) {
+	path := writeTestConfig(t, `
+records:
+  - zone: example.com
+    cert: cert.pem
+    usages: [pkix-ee]
+    matching_type: 3
+    ttl: 5
+    services:
+      - port: 70000
+        proto: icmp
+`)
+
+	_, err := loadConfig(path)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	expected := []string{
+		"records[0]: hostname is required, use \"@\" for the zone apex",
+		"records[0]: services[0].port: 70000",
+		"records[0]: services[0].proto: \"icmp\"",
+		"records[0]: usages[0]: \"pkix-ee\"",
+		"records[0]: matching_type: 3",
+		"records[0]: ttl: 5",
+	}
+	for _, msg := range expected {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected error to contain %q, got: %v", msg, err)
+		}
+	}
+}
+
+func TestLoadConfig_RecordSettings(t *testing.T) {
+	path := writeTestConfig(t, `
+defaults:
+  zone: example.com
+  cert: /etc/ssl/fullchain.pem
+  ttl: auto
+  comment: "CHG-1234 {{.Action}} by GoTLSAFlare"
+  tags: [team:mail]
+records:
+  - hostname: mail
+    services:
+      - port: 25
+  - hostname: smtp
+    ttl: 300
+    tags: [team:relay, dane]
+    services:
+      - port: 25
+`)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	all, err := config.options()
+	if err != nil {
+		t.Fatalf("options() error = %v", err)
+	}
+
+	if all[0].TTL != ttlAuto || all[0].Comment != "CHG-1234 {{.Action}} by GoTLSAFlare" || strings.Join(all[0].Tags, ",") != "team:mail" {
+		t.Errorf("Unexpected defaults applied: %+v", all[0])
+	}
+	if all[1].TTL != 300 || strings.Join(all[1].Tags, ",") != "team:relay,dane" {
+		t.Errorf("Unexpected record overrides: %+v", all[1])
+	}
+
+	path = writeTestConfig(t, `
+records:
+  - zone: example.com
+    hostname: mail
+    cert: cert.pem
+    comment: "{{.Ticket}}"
+    tags: ["bad tag"]
+    services:
+      - port: 25
+`)
+	_, err = loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "comment:") || !strings.Contains(err.Error(), "tags[0]:") {
+		t.Errorf("Expected comment and tag errors, got %v", err)
+	}
+}
+
+func TestLoadConfig_NoRecords(t *testing.T) {
+	path := writeTestConfig(t, "defaults:\n  zone: example.com\n")
+
+	if _, err := loadConfig(path); err == nil {
+		t.Error("Expected error for config without records")
+	}
+}
+
+func TestLoadTLSAOptions_FromConfig(t *testing.T) {
+	path := writeTestConfig(t, `
+records:
+  - zone: example.com
+    hostname: mail
+    cert: cert.pem
+    services:
+      - port: 25
+`)
+
+	cmd := &cobra.Command{}
+	addCreateFlags(cmd)
+	cmd.Flags().String("config", "", "Path to config file")
+
+	if err := cmd.ParseFlags([]string{"--config", path}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	all, err := loadTLSAOptions(cmd)
+	if err != nil {
+		t.Fatalf("loadTLSAOptions() error = %v", err)
+	}
+	if len(all) != 1 || all[0].Subdomain != "mail" {
+		t.Errorf("Unexpected options from config: %+v", all)
+	}
+}
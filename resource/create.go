@@ -1,182 +1,120 @@
 package resource
 
 import (
-	"bytes"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 func ResourceCreate(cmd *cobra.Command, args []string) error {
-	url, err := cmd.Flags().GetString("url")
-	if err != nil {
-		return err
-	}
-	subdomain, err := cmd.Flags().GetString("subdomain")
-	if err != nil {
-		return err
-	}
-	cert, err := cmd.Flags().GetString("cert")
-	if err != nil {
-		return err
-	}
-	tcp25, err := cmd.Flags().GetBool("tcp25")
-	if err != nil {
-		return err
-	}
-	tcp465, err := cmd.Flags().GetBool("tcp465")
-	if err != nil {
-		return err
-	}
-	tcp587, err := cmd.Flags().GetBool("tcp587")
+	all, err := loadTLSAOptions(cmd)
 	if err != nil {
 		return err
 	}
 
-	tcpPort, err := cmd.Flags().GetInt("tcp-port")
+	output, err := outputFormat(cmd)
 	if err != nil {
 		return err
 	}
 
-	daneEE, err := cmd.Flags().GetBool("dane-ee")
-	if err != nil {
+	if err := configureNotifiers(cmd); err != nil {
 		return err
 	}
 
-	noDaneEE, err := cmd.Flags().GetBool("no-dane-ee")
-	if err != nil {
+	if stop, err := handleDryRun(cmd, "create", func() ([]recordChange, error) {
+		return planCreate(all, cloudflareCredentials())
+	}); stop {
 		return err
 	}
 
-	daneTa, err := cmd.Flags().GetBool("dane-ta")
-	if err != nil {
-		return err
+	if err := preflight(cmd, cloudflareCredentials(), optionHosts(all)); err != nil {
+		return writeResults(os.Stdout, output, "create", nil, err)
 	}
 
-	selector, err := cmd.Flags().GetInt("selector")
-	if err != nil {
-		return err
-	}
+	results, err := runCreateAll(all)
+	return writeResults(os.Stdout, output, "create", results, err)
+}
 
-	matchingType, err := cmd.Flags().GetInt("matching-type")
+// runCreateAll creates the records of every set of options. The records of
+// each zone are created in one atomic batch, and nothing is created if any
+// record already exists. Zones and records are listed once for the whole run.
+func runCreateAll(all []tlsaOptions) ([]recordResult, error) {
+	snapshot, err := newProviderSnapshot(cloudflareCredentials())
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return createRecords(snapshot, all)
+}
 
-	// Handle the case where both --dane-ee and --no-dane-ee are specified
-	if noDaneEE {
-		daneEE = false
-	}
-
-	// Ensure at least one of DANE-EE or DANE-TA is enabled
-	if !daneEE && !daneTa {
-		log.Println("Error: At least one of DANE-EE or DANE-TA must be enabled")
-		os.Exit(1)
-	}
-
-	// Validate matching type
-	if matchingType != 1 && matchingType != 2 {
-		log.Println("Error: Matching type must be either 1 (SHA2-256) or 2 (SHA2-512)")
-		os.Exit(1)
-	}
-
-	createTLSARecords := func(port string) {
-		// Use appropriate selectors for each usage type if not explicitly specified
-		eeSel := selector
-		taSel := selector
-
-		// If selector is not explicitly set (-1), use defaults
-		if selector == -1 {
-			eeSel = 1 // Default to SPKI(1) for DANE-EE
-			taSel = 0 // Default to Cert(0) for DANE-TA
+// createRecords is runCreateAll with zones and records read from snapshot.
+func createRecords(snapshot *providerSnapshot, all []tlsaOptions) ([]recordResult, error) {
+	var results []recordResult
+	var zones []Zone
+	posts := make(map[string][]JSONRequest)
+	names := make(map[string][]string)
+
+	for _, opts := range all {
+		zone, err := snapshot.zoneFor(opts.host())
+		if err != nil {
+			return results, err
 		}
-
-		if daneEE {
-			postToCloudflare("_"+port+"._tcp.", url, genCloudflareReq(cert, port, "tcp", subdomain, "Created", 3, eeSel, matchingType))
+		records, err := opts.records("Created")
+		if err != nil {
+			return results, err
 		}
-
-		if daneTa {
-			postToCloudflare("_"+port+"._tcp.", url, genCloudflareReq(cert, port, "tcp", subdomain, "Created", 2, taSel, matchingType))
+		for _, record := range records {
+			name := strings.ToLower(record.Name + "." + opts.URL)
+			if err := checkNoTLSARecord(snapshot, zone.ID, name); err != nil {
+				results = append(results, newRecordResult(name, actionCreate, "", err))
+				notify(eventFailed, name, "", "Error creating TLSA record", err)
+				return results, err
+			}
+			if _, ok := posts[zone.ID]; !ok {
+				zones = append(zones, zone)
+			}
+			posts[zone.ID] = append(posts[zone.ID], record)
+			names[zone.ID] = append(names[zone.ID], name)
 		}
 	}
 
-	// Collect all ports to process
-	var ports []string
-	if tcpPort != 0 {
-		ports = append(ports, strconv.Itoa(tcpPort))
-	}
-	if tcp25 {
-		ports = append(ports, "25")
-	}
-	if tcp465 {
-		ports = append(ports, "465")
-	}
-	if tcp587 {
-		ports = append(ports, "587")
-	}
-
-	// Validate that at least one port is specified
-	if len(ports) == 0 {
-		return fmt.Errorf("no ports specified. Please specify at least one port using --tcp-port, --tcp25, --tcp465, or --tcp587")
-	}
-
-	// Process all ports
-	for _, port := range ports {
-		createTLSARecords(port)
+	for _, zone := range zones {
+		created, err := snapshot.batch(zone.ID, recordBatch{Posts: posts[zone.ID]})
+		if err != nil {
+			err = fmt.Errorf("error creating records in %s: %v", zone.Name, err)
+		}
+		for i, name := range names[zone.ID] {
+			if i >= len(created.Posts) {
+				results = append(results, newRecordResult(name, actionCreate, "", err))
+				notify(eventFailed, name, "", "Error creating TLSA record", err)
+				continue
+			}
+			id := created.Posts[i].ID
+			results = append(results, newRecordResult(name, actionCreate, id, nil))
+			slog.Info("Created TLSA record", "name", name, "id", id)
+			markSynced(name)
+			notify(eventPublished, name, id, "Created TLSA record", nil)
+		}
+		if err != nil {
+			return results, err
+		}
 	}
-
-	return nil
+	return results, nil
 }
 
-func postToCloudflare(portandprotocol string, nameanddomain string, postBody string) {
-	url := "https://api.cloudflare.com/client/v4/zones"
-	bearer := "Bearer " + os.Getenv("TOKEN")
-	// First check if record exists with either usage type (2 for DANE-TA or 3 for DANE-EE)
-	zoneID, existingRecordEE, err := getExistingRecord(url, bearer, portandprotocol, nameanddomain, 3)
+// checkNoTLSARecord fails if a DANE-EE or DANE-TA record already exists at
+// name.
+func checkNoTLSARecord(snapshot *providerSnapshot, zoneID, name string) error {
+	existing, err := snapshot.recordsAt(zoneID, name)
 	if err != nil {
-		log.Printf("Error checking for existing DANE-EE record: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error checking for existing TLSA records: %v", err)
 	}
-
-	_, existingRecordTA, err := getExistingRecord(url, bearer, portandprotocol, nameanddomain, 2)
-	if err != nil {
-		log.Printf("Error checking for existing DANE-TA record: %v\n", err)
-		os.Exit(1)
-	}
-
-	if existingRecordEE != nil || existingRecordTA != nil {
-		log.Printf("Error: TLSA record already exists for %s%s\n", portandprotocol, nameanddomain)
-		os.Exit(1)
-	}
-
-	if zoneID == "" {
-		log.Println("Error: Could not find zone ID")
-		os.Exit(1)
-	}
-
-	posturl := "https://api.cloudflare.com/client/v4/zones/" + zoneID + "/dns_records"
-
-	var jsonStr = []byte(postBody)
-	req2, err2 := http.NewRequest("POST", posturl, bytes.NewBuffer(jsonStr))
-	if err2 != nil {
-		log.Println(err2)
-		os.Exit(1)
-	}
-
-	req2.Header.Set("Content-Type", "application/json")
-	req2.Header.Add("Authorization", bearer)
-
-	client2 := &http.Client{}
-	resp2, err2 := client2.Do(req2)
-	if err2 != nil {
-		log.Println(err2)
-		os.Exit(1)
+	for _, have := range existing {
+		if have.Data.Usage == 3 || have.Data.Usage == 2 {
+			return fmt.Errorf("TLSA record already exists for %s", name)
+		}
 	}
-	defer resp2.Body.Close()
-
-	fmt.Println("Cloudflare Response Status:", resp2.Status)
+	return nil
 }
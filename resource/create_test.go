@@ -279,3 +279,29 @@ func TestResourceCreate_CustomPort(t *testing.T) {
 		t.Errorf("Expected tcp-port 8443, got %d", tcpPort)
 	}
 }
+
+func TestRunCreateAll_ListsOnce(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com", "example.org")
+	mail := syncTestOptions(t)
+	mail.Services = append(mail.Services, tlsaService{Port: "465", Protocol: "tcp"})
+	xmpp := syncTestOptions(t)
+	xmpp.URL = "example.org"
+	xmpp.Subdomain = "xmpp"
+
+	results, err := runCreateAll([]tlsaOptions{mail, xmpp})
+	if err != nil {
+		t.Fatalf("runCreateAll() error = %v", err)
+	}
+	if len(results) != 5 {
+		t.Errorf("Expected 5 created records, got %d", len(results))
+	}
+
+	if n := f.countCalls("GET /zones") - f.countCalls("GET /zones/"); n != 1 {
+		t.Errorf("Expected 1 zone listing, got %d", n)
+	}
+	for _, zoneID := range []string{"zone-1", "zone-2"} {
+		if n := f.countCalls("GET /zones/" + zoneID + "/dns_records"); n != 1 {
+			t.Errorf("Expected 1 record listing for %s, got %d", zoneID, n)
+		}
+	}
+}
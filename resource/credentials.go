@@ -0,0 +1,290 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// cloudflareAuth is the credential sent with every API request: an API
+// token, or the legacy Global API Key with its account email. It never
+// prints its secret.
+type cloudflareAuth struct {
+	token string
+	email string
+	key   string
+}
+
+func (a cloudflareAuth) apply(req *http.Request) {
+	if a.key != "" {
+		req.Header.Set("X-Auth-Email", a.email)
+		req.Header.Set("X-Auth-Key", a.key)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+}
+
+func (a cloudflareAuth) String() string {
+	if a.key != "" {
+		return "global API key for " + a.email
+	}
+	return "API token " + redacted
+}
+
+func (a cloudflareAuth) GoString() string { return a.String() }
+
+func (a cloudflareAuth) LogValue() slog.Value { return slog.StringValue(a.String()) }
+
+// credentialsFile holds named profiles, by default in
+// $XDG_CONFIG_HOME/gotlsaflare/credentials.yaml:
+//
+//	profiles:
+//	  default:
+//	    token: ...
+//	  systemd:
+//	    token_file: /run/credentials/gotlsaflare.service/cloudflare
+//	  legacy:
+//	    email: hostmaster@example.com
+//	    api_key: ...
+type credentialsFile struct {
+	Profiles map[string]credentialsProfile `yaml:"profiles"`
+}
+
+type credentialsProfile struct {
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+	Email     string `yaml:"email"`
+	APIKey    string `yaml:"api_key"`
+}
+
+// activeAuth is set by ConfigureCredentials when a credential flag is given.
+// Otherwise credentials come from the environment on every call.
+var activeAuth *cloudflareAuth
+
+// cloudflareCredentials returns the credential for API requests.
+func cloudflareCredentials() cloudflareAuth {
+	if activeAuth != nil {
+		return *activeAuth
+	}
+	auth, _ := credentialsFromEnv()
+	return auth
+}
+
+// ConfigureCredentials resolves --token-file, then --profile, then the TOKEN
+// environment variable, then CLOUDFLARE_EMAIL and CLOUDFLARE_API_KEY.
+func ConfigureCredentials(cmd *cobra.Command, args []string) error {
+	tokenFile, _ := cmd.Flags().GetString("token-file")
+	profile, _ := cmd.Flags().GetString("profile")
+	credentialsPath, _ := cmd.Flags().GetString("credentials-file")
+	if profile == "" {
+		profile = os.Getenv("GOTLSAFLARE_PROFILE")
+	}
+
+	auth, err := resolveCredentials(tokenFile, profile, credentialsPath)
+	if err != nil {
+		return err
+	}
+	activeAuth = auth
+	return nil
+}
+
+// resolveCredentials returns nil when neither a token file nor a profile is
+// requested, leaving the environment in charge.
+func resolveCredentials(tokenFile, profile, credentialsPath string) (*cloudflareAuth, error) {
+	switch {
+	case tokenFile != "":
+		token, err := readTokenFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		return &cloudflareAuth{token: token}, nil
+	case profile != "":
+		auth, err := loadProfile(credentialsPath, profile)
+		if err != nil {
+			return nil, err
+		}
+		return &auth, nil
+	}
+	return nil, nil
+}
+
+func credentialsFromEnv() (cloudflareAuth, bool) {
+	if token := os.Getenv("TOKEN"); token != "" {
+		registerSecret(token)
+		return cloudflareAuth{token: token}, true
+	}
+	if key := os.Getenv("CLOUDFLARE_API_KEY"); key != "" {
+		registerSecret(key)
+		return cloudflareAuth{email: os.Getenv("CLOUDFLARE_EMAIL"), key: key}, true
+	}
+	return cloudflareAuth{}, false
+}
+
+// readTokenFile reads a token from a file. Relative paths are looked up in
+// $CREDENTIALS_DIRECTORY first, where systemd's LoadCredential= puts them.
+func readTokenFile(path string) (string, error) {
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" && !filepath.IsAbs(path) {
+		if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+			path = filepath.Join(dir, path)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading token file: %v", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	registerSecret(token)
+	return token, nil
+}
+
+func defaultCredentialsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gotlsaflare", "credentials.yaml")
+}
+
+func loadProfile(path, name string) (cloudflareAuth, error) {
+	if path == "" {
+		path = defaultCredentialsPath()
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cloudflareAuth{}, fmt.Errorf("error reading credentials file: %v", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+		slog.Warn("Credentials file is readable by other users", "path", path, "mode", info.Mode().Perm().String())
+	}
+
+	var file credentialsFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		// yaml errors quote the offending line, which may hold a secret
+		return cloudflareAuth{}, fmt.Errorf("error parsing credentials file %s", path)
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok {
+		return cloudflareAuth{}, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+
+	switch {
+	case profile.Token != "" && profile.TokenFile == "" && profile.APIKey == "":
+		registerSecret(profile.Token)
+		return cloudflareAuth{token: profile.Token}, nil
+	case profile.TokenFile != "" && profile.Token == "" && profile.APIKey == "":
+		token, err := readTokenFile(profile.TokenFile)
+		if err != nil {
+			return cloudflareAuth{}, err
+		}
+		return cloudflareAuth{token: token}, nil
+	case profile.APIKey != "" && profile.Token == "" && profile.TokenFile == "":
+		if profile.Email == "" {
+			return cloudflareAuth{}, fmt.Errorf("profile %q: email is required with api_key", name)
+		}
+		registerSecret(profile.APIKey)
+		return cloudflareAuth{email: profile.Email, key: profile.APIKey}, nil
+	}
+	return cloudflareAuth{}, fmt.Errorf("profile %q: exactly one of token, token_file or api_key is required", name)
+}
+
+var (
+	secretsMu sync.Mutex
+	secrets   []string
+
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"']+`)
+)
+
+// registerSecret adds a credential to be scrubbed by Redact.
+func registerSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, known := range secrets {
+		if known == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// Redact removes credentials from text destined for logs or error output.
+func Redact(s string) string {
+	secretsMu.Lock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	secretsMu.Unlock()
+	return bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redactHandler scrubs credentials from log messages and attributes.
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = redactAttr(a)
+	}
+	return redactHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]any, len(group))
+		for i, ga := range group {
+			clean[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, clean...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
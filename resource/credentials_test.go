@@ -0,0 +1,223 @@
+package resource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func writeCredentialsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write credentials file: %v", err)
+	}
+	return path
+}
+
+func TestCloudflareAuth_Apply(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.com", nil)
+	cloudflareAuth{token: "secret-token"}.apply(req)
+	if req.Header.Get("Authorization") != "Bearer secret-token" {
+		t.Errorf("Unexpected Authorization header: %s", req.Header.Get("Authorization"))
+	}
+
+	req, _ = http.NewRequest("GET", "https://example.com", nil)
+	cloudflareAuth{email: "hostmaster@example.com", key: "secret-key"}.apply(req)
+	if req.Header.Get("X-Auth-Email") != "hostmaster@example.com" || req.Header.Get("X-Auth-Key") != "secret-key" || req.Header.Get("Authorization") != "" {
+		t.Errorf("Unexpected global API key headers: %v", req.Header)
+	}
+}
+
+func TestCloudflareAuth_NeverPrintsSecret(t *testing.T) {
+	for _, auth := range []cloudflareAuth{{token: "secret-token"}, {email: "hostmaster@example.com", key: "secret-key"}} {
+		var logs bytes.Buffer
+		slog.New(slog.NewTextHandler(&logs, nil)).Info("Using credentials", "auth", auth)
+
+		for _, out := range []string{fmt.Sprint(auth), fmt.Sprintf("%+v", auth), fmt.Sprintf("%#v", auth), logs.String()} {
+			if strings.Contains(out, "secret") {
+				t.Errorf("Credential leaked: %s", out)
+			}
+		}
+	}
+}
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cloudflare"), []byte("file-token\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	token, err := readTokenFile("cloudflare")
+	if err != nil {
+		t.Fatalf("readTokenFile() error = %v", err)
+	}
+	if token != "file-token" {
+		t.Errorf("Expected trimmed token, got %q", token)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	os.WriteFile(empty, []byte("\n"), 0o600)
+	if _, err := readTokenFile(empty); err == nil {
+		t.Error("Expected error for empty token file")
+	}
+	if _, err := readTokenFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing token file")
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("file-token"), 0o600)
+
+	path := writeCredentialsFile(t, `profiles:
+  default:
+    token: profile-token
+  systemd:
+    token_file: `+tokenFile+`
+  legacy:
+    email: hostmaster@example.com
+    api_key: legacy-key
+  nomail:
+    api_key: legacy-key
+  both:
+    token: a
+    api_key: b
+`)
+
+	testCases := []struct {
+		profile string
+		want    cloudflareAuth
+		wantErr bool
+	}{
+		{"default", cloudflareAuth{token: "profile-token"}, false},
+		{"systemd", cloudflareAuth{token: "file-token"}, false},
+		{"legacy", cloudflareAuth{email: "hostmaster@example.com", key: "legacy-key"}, false},
+		{"nomail", cloudflareAuth{}, true},
+		{"both", cloudflareAuth{}, true},
+		{"missing", cloudflareAuth{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			got, err := loadProfile(path, tc.profile)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("loadProfile() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("loadProfile(%s) returned an unexpected credential", tc.profile)
+			}
+		})
+	}
+}
+
+func TestLoadProfile_ParseErrorHidesContent(t *testing.T) {
+	path := writeCredentialsFile(t, "profiles:\n  default:\n    tokn: leaked-secret\n")
+
+	_, err := loadProfile(path, "default")
+	if err == nil {
+		t.Fatal("Expected parse error for unknown key")
+	}
+	if strings.Contains(err.Error(), "leaked-secret") {
+		t.Errorf("Parse error leaked file content: %v", err)
+	}
+}
+
+func TestConfigureCredentials_Precedence(t *testing.T) {
+	t.Cleanup(func() { activeAuth = nil })
+	t.Setenv("TOKEN", "env-token")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("file-token"), 0o600)
+	path := writeCredentialsFile(t, "profiles:\n  default:\n    token: profile-token\n")
+
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("token-file", "", "")
+		cmd.Flags().String("profile", "", "")
+		cmd.Flags().String("credentials-file", "", "")
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+		return cmd
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Environment", nil, "env-token"},
+		{"Profile", []string{"--profile", "default", "--credentials-file", path}, "profile-token"},
+		{"TokenFileWins", []string{"--token-file", tokenFile, "--profile", "default", "--credentials-file", path}, "file-token"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ConfigureCredentials(newCmd(tc.args...), nil); err != nil {
+				t.Fatalf("ConfigureCredentials() error = %v", err)
+			}
+			if got := cloudflareCredentials().token; got != tc.want {
+				t.Errorf("Expected token %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	if err := ConfigureCredentials(newCmd("--token-file", filepath.Join(t.TempDir(), "missing")), nil); err == nil {
+		t.Error("Expected error for missing token file")
+	}
+}
+
+func TestCredentialsFromEnv_GlobalAPIKey(t *testing.T) {
+	newFakeCloudflare(t, "example.com")
+	t.Setenv("TOKEN", "")
+	t.Setenv("CLOUDFLARE_EMAIL", "hostmaster@example.com")
+	t.Setenv("CLOUDFLARE_API_KEY", "test-key")
+
+	zones, err := listZones(cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("listZones() with global API key error = %v", err)
+	}
+	if len(zones) != 1 {
+		t.Errorf("Expected 1 zone, got %d", len(zones))
+	}
+}
+
+func TestRedact(t *testing.T) {
+	registerSecret("registered-secret")
+
+	got := Redact(`GET /zones failed: token registered-secret rejected, header "Authorization: Bearer abc.def-123"`)
+	if strings.Contains(got, "registered-secret") || strings.Contains(got, "abc.def-123") {
+		t.Errorf("Redact() left a secret: %s", got)
+	}
+	if !strings.Contains(got, "Bearer [REDACTED]") {
+		t.Errorf("Expected bearer value to be redacted, got: %s", got)
+	}
+}
+
+func TestRedactHandler(t *testing.T) {
+	registerSecret("handler-secret")
+
+	var buf bytes.Buffer
+	handler, err := newLogHandler(&buf, "json", "info")
+	if err != nil {
+		t.Fatalf("newLogHandler() error = %v", err)
+	}
+	logger := slog.New(handler).With("token", "handler-secret")
+	logger.Error("request with handler-secret failed",
+		"error", errors.New("bad token handler-secret"),
+		slog.Group("request", "auth", "Bearer handler-secret"))
+
+	if strings.Contains(buf.String(), "handler-secret") {
+		t.Errorf("Secret leaked into logs: %s", buf.String())
+	}
+}
@@ -0,0 +1,169 @@
+package resource
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func ResourceDelete(cmd *cobra.Command, args []string) error {
+	deleteAll, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return err
+	}
+
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+
+	var all []tlsaOptions
+	configPath, _ := cmd.Flags().GetString("config")
+	fromMX, _ := cmd.Flags().GetString("from-mx")
+	if configPath != "" || fromMX != "" {
+		all, err = loadTLSAOptions(cmd)
+	} else {
+		var opts tlsaOptions
+		opts, err = parseTLSASelection(cmd)
+		if err == nil && !deleteAll {
+			err = opts.validate()
+		}
+		all = []tlsaOptions{opts}
+	}
+	if err != nil {
+		return err
+	}
+
+	auth := cloudflareCredentials()
+	plan := func() ([]recordChange, error) {
+		return planDelete(all, auth, deleteAll, force)
+	}
+
+	if stop, err := handleDryRun(cmd, "delete", plan); stop {
+		return err
+	}
+
+	changes, err := plan()
+	if err != nil {
+		return err
+	}
+
+	printPlan(os.Stdout, changes)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := preflight(cmd, auth, changeNames(changes)); err != nil {
+		return err
+	}
+
+	if !yes && !confirm(cmd.InOrStdin(), os.Stdout, fmt.Sprintf("Delete %d TLSA record(s)?", len(changes))) {
+		return fmt.Errorf("aborted, no records deleted")
+	}
+
+	return applyChanges(changes, auth)
+}
+
+// planDelete selects the TLSA records to delete: those matching the
+// services and usages of the options (and their certificate, if set), or
+// every TLSA record of the host with deleteAll. Records not carrying
+// managedMarker are refused unless force is set.
+func planDelete(all []tlsaOptions, auth cloudflareAuth, deleteAll bool, force bool) ([]recordChange, error) {
+	snapshot, err := newProviderSnapshot(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []recordChange
+	var unmanaged []string
+	seen := make(map[string]bool)
+
+	add := func(zoneID string, record DNSRecord) {
+		if seen[record.ID] {
+			return
+		}
+		seen[record.ID] = true
+		if !isManaged(record) && !force {
+			unmanaged = append(unmanaged, record.Name+" "+formatTLSAData(record.Data.Usage, record.Data.Selector, record.Data.MatchingType, record.Data.Certificate))
+			return
+		}
+		old := record
+		changes = append(changes, recordChange{Action: actionDelete, ZoneID: zoneID, Name: strings.ToLower(record.Name), Old: &old})
+	}
+
+	for _, opts := range all {
+		zone, err := snapshot.zoneFor(opts.host())
+		if err != nil {
+			return nil, err
+		}
+		records, err := snapshot.tlsaRecords(zone.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if deleteAll {
+			for _, record := range records {
+				name := strings.ToLower(record.Name)
+				if name != opts.host() && tlsaHost(name) == opts.host() {
+					add(zone.ID, record)
+				}
+			}
+			continue
+		}
+
+		var wanted []JSONRequest
+		if opts.Cert != "" {
+			if wanted, err = opts.records("Deleted"); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, svc := range opts.Services {
+			name := strings.ToLower(svc.prefix() + opts.host())
+			for _, record := range records {
+				if !strings.EqualFold(record.Name, name) || !opts.hasUsage(record.Data.Usage) {
+					continue
+				}
+				if opts.Cert != "" && !matchesAny(wanted, record) {
+					continue
+				}
+				add(zone.ID, record)
+			}
+		}
+	}
+
+	if len(unmanaged) > 0 {
+		return nil, fmt.Errorf("refusing to delete TLSA records not managed by gotlsaflare (use --force):\n  %s", strings.Join(unmanaged, "\n  "))
+	}
+	return changes, nil
+}
+
+func (o tlsaOptions) hasUsage(usage int) bool {
+	return (usage == 3 && o.DaneEE) || (usage == 2 && o.DaneTA)
+}
+
+func matchesAny(wanted []JSONRequest, record DNSRecord) bool {
+	for _, want := range wanted {
+		if sameTLSAData(want, record) {
+			return true
+		}
+	}
+	return false
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func confirm(in io.Reader, out io.Writer, prompt string) bool {
+	fmt.Fprintf(out, "%s [y/N]: ", prompt)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,134 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const managedComment = "Created by GoTLSAFlare - 2024-01-01 00:00:00"
+
+func addDeleteFlags(cmd *cobra.Command) {
+	addCreateFlags(cmd)
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.Flags().String("save-plan", "", "")
+	cmd.Flags().Bool("all", false, "")
+	cmd.Flags().Bool("yes", false, "")
+	cmd.Flags().Bool("force", false, "")
+}
+
+func TestPlanDelete_ByServiceAndUsage(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	ee := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", managedComment)
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 2, 0, 1, "bbbb", managedComment)
+	f.addRecord("zone-1", "_465._tcp.mail.example.com", 3, 1, 1, "cccc", managedComment)
+
+	opts := tlsaOptions{URL: "example.com", Subdomain: "mail", Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true}
+
+	changes, err := planDelete([]tlsaOptions{opts}, cloudflareCredentials(), false, false)
+	if err != nil {
+		t.Fatalf("planDelete() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Old.ID != ee.ID {
+		t.Errorf("Expected only the DANE-EE record on port 25, got %+v", changes)
+	}
+}
+
+func TestPlanDelete_All(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", managedComment)
+	f.addRecord("zone-1", "_465._tcp.mail.example.com", 2, 0, 1, "bbbb", managedComment)
+	f.addRecord("zone-1", "_25._tcp.mx.example.com", 3, 1, 1, "cccc", managedComment)
+
+	opts := tlsaOptions{URL: "example.com", Subdomain: "mail"}
+
+	changes, err := planDelete([]tlsaOptions{opts}, cloudflareCredentials(), true, false)
+	if err != nil {
+		t.Fatalf("planDelete() error = %v", err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("Expected both mail records, got %d", len(changes))
+	}
+}
+
+func TestPlanDelete_RefusesUnmanaged(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", "hand made")
+
+	opts := tlsaOptions{URL: "example.com", Subdomain: "mail", Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true}
+
+	_, err := planDelete([]tlsaOptions{opts}, cloudflareCredentials(), false, false)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected refusal for unmanaged record, got %v", err)
+	}
+
+	changes, err := planDelete([]tlsaOptions{opts}, cloudflareCredentials(), false, true)
+	if err != nil || len(changes) != 1 {
+		t.Errorf("Expected forced delete of unmanaged record, got %v, %v", changes, err)
+	}
+}
+
+func TestPlanDelete_MatchingCertificate(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = opts.Services[:1]
+	eeHash, _, err := opts.hashes()
+	if err != nil {
+		t.Fatalf("hashes() error = %v", err)
+	}
+
+	current := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, eeHash, managedComment)
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "0000", managedComment)
+
+	changes, err := planDelete([]tlsaOptions{opts}, cloudflareCredentials(), false, false)
+	if err != nil {
+		t.Fatalf("planDelete() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Old.ID != current.ID {
+		t.Errorf("Expected only the record matching the certificate, got %+v", changes)
+	}
+}
+
+func TestResourceDelete_Confirmation(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", managedComment)
+
+	args := []string{"--url", "example.com", "--subdomain", "mail", "--tcp25"}
+
+	cmd := &cobra.Command{}
+	addDeleteFlags(cmd)
+	cmd.SetIn(strings.NewReader("n\n"))
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if err := ResourceDelete(cmd, nil); err == nil {
+		t.Error("Expected abort error when confirmation is declined")
+	}
+	if len(f.zoneRecords("zone-1")) != 1 {
+		t.Fatal("Record must not be deleted without confirmation")
+	}
+
+	cmd = &cobra.Command{}
+	addDeleteFlags(cmd)
+	cmd.SetIn(strings.NewReader("y\n"))
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if err := ResourceDelete(cmd, nil); err != nil {
+		t.Fatalf("ResourceDelete() error = %v", err)
+	}
+	if len(f.zoneRecords("zone-1")) != 0 {
+		t.Error("Expected record to be deleted after confirmation")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	var out strings.Builder
+	for input, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		if got := confirm(strings.NewReader(input), &out, "Delete?"); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
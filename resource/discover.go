@@ -0,0 +1,220 @@
+package resource
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+)
+
+// srvServices are the SRV services --srv accepts by short name. Any other
+// service can be given as _service._proto.
+var srvServices = map[string]string{
+	"submission":  "_submission._tcp",
+	"submissions": "_submissions._tcp",
+	"imap":        "_imap._tcp",
+	"imaps":       "_imaps._tcp",
+	"pop3s":       "_pop3s._tcp",
+	"xmpp-client": "_xmpp-client._tcp",
+	"xmpp-server": "_xmpp-server._tcp",
+}
+
+// discoveredTarget is a host and service found in the MX or SRV records of a
+// domain, where SMTP and SRV clients look up the TLSA records.
+type discoveredTarget struct {
+	Host    string
+	Service tlsaService
+	Source  string
+}
+
+// discoverTLSAOptions returns the options for --from-mx: one set per target
+// host in a zone that can be managed, with the certificate and usages of the
+// flags. Targets outside those zones are logged and skipped.
+func discoverTLSAOptions(cmd *cobra.Command, domain string) ([]tlsaOptions, error) {
+	template, err := parseTLSASelection(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if !cmd.Flags().Changed("cert") {
+		return nil, fmt.Errorf("required flag \"cert\" not set")
+	}
+
+	services, err := cmd.Flags().GetStringSlice("srv")
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := cmd.Flags().GetString("resolver")
+	if err != nil {
+		return nil, err
+	}
+	if resolver == "" {
+		resolver = systemResolver()
+	}
+
+	targets, err := discoverTargets(domain, services, resolver)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := providerFor(cloudflareCredentials()).zones()
+	if err != nil {
+		return nil, err
+	}
+
+	all := discoveredOptions(template, targets, zones)
+	if len(all) == 0 {
+		return nil, fmt.Errorf("none of the TLSA targets of %s are in a zone that can be managed", domain)
+	}
+	for _, opts := range all {
+		if err := opts.validate(); err != nil {
+			return nil, err
+		}
+		warnCertCoverage(opts)
+	}
+	return all, nil
+}
+
+// discoverTargets resolves the MX records of domain, each target taking TLSA
+// records for port 25, and the SRV records of services, each target taking
+// them for the port and protocol of the SRV record.
+func discoverTargets(domain string, services []string, resolver string) ([]discoveredTarget, error) {
+	answers, err := lookupRecords(resolver, domain, dns.TypeMX)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []discoveredTarget
+	for _, rr := range answers {
+		mx, ok := rr.(*dns.MX)
+		if !ok {
+			continue
+		}
+		if mx.Mx == "." {
+			slog.Warn("Domain publishes a null MX and accepts no mail", "domain", domain)
+			continue
+		}
+		targets = append(targets, discoveredTarget{Host: targetHost(mx.Mx), Service: tlsaService{Port: "25", Protocol: "tcp"}, Source: "MX"})
+	}
+	if len(targets) == 0 && len(services) == 0 {
+		return nil, fmt.Errorf("no MX records found for %s", domain)
+	}
+
+	for _, service := range services {
+		prefix, err := srvPrefix(service)
+		if err != nil {
+			return nil, err
+		}
+		answers, err := lookupRecords(resolver, prefix+"."+domain, dns.TypeSRV)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, rr := range answers {
+			srv, ok := rr.(*dns.SRV)
+			if !ok || srv.Target == "." {
+				continue
+			}
+			found = true
+			protocol := strings.TrimPrefix(strings.SplitN(prefix, ".", 2)[1], "_")
+			targets = append(targets, discoveredTarget{Host: targetHost(srv.Target), Service: tlsaService{Port: strconv.Itoa(int(srv.Port)), Protocol: protocol}, Source: prefix})
+		}
+		if !found {
+			slog.Warn("No SRV records found", "name", prefix+"."+domain)
+		}
+	}
+
+	slices.SortFunc(targets, func(a, b discoveredTarget) int {
+		return cmp.Or(cmp.Compare(a.Host, b.Host), cmp.Compare(a.Service.Protocol, b.Service.Protocol), cmp.Compare(a.Service.Port, b.Service.Port))
+	})
+	return slices.CompactFunc(targets, func(a, b discoveredTarget) bool {
+		return a.Host == b.Host && a.Service == b.Service
+	}), nil
+}
+
+// discoveredOptions groups the targets by host into options for the zone of
+// each host, copying everything but the services from template.
+func discoveredOptions(template tlsaOptions, targets []discoveredTarget, zones []Zone) []tlsaOptions {
+	var all []tlsaOptions
+	index := make(map[string]int)
+	for _, target := range targets {
+		zone, ok := findZone(zones, target.Host)
+		if !ok {
+			slog.Warn("TLSA target is outside the zones that can be managed, publish its records where it is hosted", "host", target.Host, "source", target.Source)
+			continue
+		}
+
+		i, ok := index[target.Host]
+		if !ok {
+			opts := optionsAt(template, target.Host, zone)
+			opts.Services = nil
+			i = len(all)
+			index[target.Host] = i
+			all = append(all, opts)
+		}
+		all[i].Services = append(all[i].Services, target.Service)
+		slog.Info("Found TLSA target", "host", target.Host, "port", target.Service.Port, "protocol", target.Service.Protocol, "source", target.Source)
+	}
+	return all
+}
+
+// optionsAt returns template moved to host in zone.
+func optionsAt(template tlsaOptions, host string, zone Zone) tlsaOptions {
+	opts := template
+	opts.URL = strings.ToLower(zone.Name)
+	opts.Subdomain = apexSubdomain
+	if host != opts.URL {
+		opts.Subdomain = strings.TrimSuffix(host, "."+opts.URL)
+	}
+	return opts
+}
+
+// srvPrefix returns the _service._proto labels of a --srv value.
+func srvPrefix(service string) (string, error) {
+	if prefix, ok := srvServices[strings.ToLower(service)]; ok {
+		return prefix, nil
+	}
+	labels := strings.Split(strings.ToLower(service), ".")
+	if len(labels) == 2 && len(labels[0]) > 1 && strings.HasPrefix(labels[0], "_") && (labels[1] == "_tcp" || labels[1] == "_udp" || labels[1] == "_sctp") {
+		return labels[0] + "." + labels[1], nil
+	}
+	return "", fmt.Errorf("unknown SRV service %q, use one of submission, submissions, imap, imaps, pop3s, xmpp-client, xmpp-server or _service._proto", service)
+}
+
+func targetHost(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// lookupRecords queries resolver for the records of type qtype at name. A
+// name that does not exist has no records.
+func lookupRecords(resolver, name string, qtype uint16) ([]dns.RR, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = true
+
+	r, _, err := new(dns.Client).Exchange(m, resolver)
+	if err != nil {
+		return nil, fmt.Errorf("DNS query for %s %s failed: %v", name, dns.TypeToString[qtype], err)
+	}
+	switch r.Rcode {
+	case dns.RcodeSuccess:
+		return r.Answer, nil
+	case dns.RcodeNameError:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("DNS query for %s %s failed: %s", name, dns.TypeToString[qtype], dns.RcodeToString[r.Rcode])
+}
+
+// systemResolver returns the first nameserver of /etc/resolv.conf, or a
+// public resolver when there is none.
+func systemResolver() string {
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil || len(config.Servers) == 0 {
+		return "1.1.1.1:53"
+	}
+	return net.JoinHostPort(config.Servers[0], config.Port)
+}
@@ -0,0 +1,135 @@
+package resource
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+)
+
+// newFakeResolver serves the given records over UDP on localhost and returns
+// its address. Names without records answer NXDOMAIN.
+func newFakeResolver(t *testing.T, records ...string) string {
+	t.Helper()
+
+	answers := make(map[string][]dns.RR)
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatalf("Invalid test record %q: %v", record, err)
+		}
+		key := strings.ToLower(rr.Header().Name) + " " + dns.TypeToString[rr.Header().Rrtype]
+		answers[key] = append(answers[key], rr)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		q := req.Question[0]
+		m.Answer = answers[strings.ToLower(q.Name)+" "+dns.TypeToString[q.Qtype]]
+		if m.Answer == nil {
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestDiscoverTargets(t *testing.T) {
+	resolver := newFakeResolver(t,
+		"example.com. 300 IN MX 10 mx1.example.com.",
+		"example.com. 300 IN MX 20 MX.Hosted.Net.",
+		"_submission._tcp.example.com. 300 IN SRV 0 1 587 mail.example.com.",
+		"_imaps._tcp.example.com. 300 IN SRV 0 1 993 mail.example.com.",
+		"_imaps._tcp.example.com. 300 IN SRV 10 1 993 mail.example.com.",
+	)
+
+	targets, err := discoverTargets("example.com", []string{"submission", "_imaps._tcp", "xmpp-server"}, resolver)
+	if err != nil {
+		t.Fatalf("discoverTargets() error = %v", err)
+	}
+
+	var got []string
+	for _, target := range targets {
+		got = append(got, target.Service.prefix()+target.Host+" ("+target.Source+")")
+	}
+	want := "_587._tcp.mail.example.com (_submission._tcp),_993._tcp.mail.example.com (_imaps._tcp),_25._tcp.mx.hosted.net (MX),_25._tcp.mx1.example.com (MX)"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Unexpected targets:\n got %s\nwant %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestDiscoverTargets_NoMX(t *testing.T) {
+	resolver := newFakeResolver(t, "example.com. 300 IN MX 0 .")
+
+	if _, err := discoverTargets("example.com", nil, resolver); err == nil || !strings.Contains(err.Error(), "no MX records found") {
+		t.Errorf("Expected error for null MX, got %v", err)
+	}
+	if _, err := discoverTargets("example.org", nil, resolver); err == nil {
+		t.Error("Expected error for domain without MX")
+	}
+}
+
+func TestSrvPrefix(t *testing.T) {
+	testCases := []struct {
+		service, want string
+	}{
+		{"submission", "_submission._tcp"},
+		{"XMPP-Server", "_xmpp-server._tcp"},
+		{"_sip._udp", "_sip._udp"},
+		{"sip", ""},
+		{"_sip", ""},
+		{"_._tcp", ""},
+	}
+
+	for _, tc := range testCases {
+		got, err := srvPrefix(tc.service)
+		if got != tc.want || (err != nil) != (tc.want == "") {
+			t.Errorf("srvPrefix(%q) = %q, %v, want %q", tc.service, got, err, tc.want)
+		}
+	}
+}
+
+func TestLoadTLSAOptions_FromMX(t *testing.T) {
+	newFakeCloudflare(t, "example.com", "mail.example.org")
+	resolver := newFakeResolver(t,
+		"example.net. 300 IN MX 10 mx1.example.com.",
+		"example.net. 300 IN MX 20 mail.example.org.",
+		"example.net. 300 IN MX 30 mx.hosted.net.",
+		"_submission._tcp.example.net. 300 IN SRV 0 1 587 mx1.example.com.",
+	)
+
+	cmd := &cobra.Command{}
+	addCreateFlags(cmd)
+	cmd.Flags().String("from-mx", "", "")
+	cmd.Flags().StringSlice("srv", nil, "")
+	cmd.Flags().String("resolver", "", "")
+	if err := cmd.ParseFlags([]string{"--from-mx", "example.net", "--srv", "submission", "--resolver", resolver, "--cert", generateTestCertForReq(t)}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	all, err := loadTLSAOptions(cmd)
+	if err != nil {
+		t.Fatalf("loadTLSAOptions() error = %v", err)
+	}
+
+	// mx.hosted.net is outside the account and skipped
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 hosts, got %+v", all)
+	}
+	apex, mx1 := all[0], all[1]
+	if apex.URL != "mail.example.org" || apex.Subdomain != apexSubdomain || len(apex.Services) != 1 {
+		t.Errorf("Unexpected options for the apex target: %+v", apex)
+	}
+	if mx1.URL != "example.com" || mx1.Subdomain != "mx1" || len(mx1.Services) != 2 || mx1.Services[1].Port != "587" || !mx1.DaneEE {
+		t.Errorf("Unexpected options for mx1: %+v", mx1)
+	}
+}
@@ -0,0 +1,108 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Doctor check statuses.
+const (
+	doctorOK   = "OK"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+)
+
+type doctorCheck struct {
+	Status  string
+	Subject string
+	Detail  string
+}
+
+func ResourceDoctor(cmd *cobra.Command, args []string) error {
+	urls, err := cmd.Flags().GetStringSlice("url")
+	if err != nil {
+		return err
+	}
+
+	checks := runDoctor(cloudflareCredentials(), urls)
+	if failed := writeDoctorReport(os.Stdout, checks); failed > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", failed)
+	}
+	return nil
+}
+
+// runDoctor checks the credential, then read and write access and DNSSEC
+// for the zones of urls, or every visible zone when urls is empty.
+func runDoctor(auth cloudflareAuth, urls []string) []doctorCheck {
+	var checks []doctorCheck
+
+	detail, err := verifyToken(auth)
+	if err != nil {
+		return append(checks, doctorCheck{doctorFail, "token", err.Error()})
+	}
+	checks = append(checks, doctorCheck{doctorOK, "token", detail})
+
+	visible, err := listZones(auth)
+	if err != nil {
+		return append(checks, doctorCheck{doctorFail, "zones", err.Error()})
+	}
+
+	var zones []Zone
+	if len(urls) == 0 {
+		zones = visible
+	}
+	for _, url := range urls {
+		zone, ok := findZone(visible, strings.ToLower(url))
+		if !ok {
+			checks = append(checks, doctorCheck{doctorFail, url, "no zone visible to the token"})
+			continue
+		}
+		zones = append(zones, zone)
+	}
+	if len(zones) == 0 && len(urls) == 0 {
+		return append(checks, doctorCheck{doctorFail, "zones", "no zones visible to the token"})
+	}
+	if len(urls) == 0 {
+		checks = append(checks, doctorCheck{doctorOK, "zones", fmt.Sprintf("%d zone(s) visible", len(zones))})
+	}
+
+	for _, zone := range zones {
+		err := checkZoneAccess(auth, zone)
+		switch {
+		case errors.Is(err, errWriteUnconfirmed):
+			checks = append(checks, doctorCheck{doctorWarn, zone.Name, "TLSA records can be read, " + err.Error()})
+		case err != nil:
+			checks = append(checks, doctorCheck{doctorFail, zone.Name, err.Error()})
+		default:
+			checks = append(checks, doctorCheck{doctorOK, zone.Name, "TLSA records can be read and probably written (write probe)"})
+		}
+
+		status, err := dnssecStatus(auth, zone)
+		switch {
+		case err != nil:
+			checks = append(checks, doctorCheck{doctorWarn, zone.Name, "DNSSEC status unavailable: " + err.Error()})
+		case status == "active":
+			checks = append(checks, doctorCheck{doctorOK, zone.Name, "DNSSEC is active"})
+		default:
+			checks = append(checks, doctorCheck{doctorWarn, zone.Name, "DNSSEC is " + status + ", TLSA records are ignored by DANE validators without it"})
+		}
+	}
+	return checks
+}
+
+// writeDoctorReport prints the checks and returns how many failed.
+func writeDoctorReport(w io.Writer, checks []doctorCheck) int {
+	failed := 0
+	for _, check := range checks {
+		if check.Status == doctorFail {
+			failed++
+		}
+		fmt.Fprintf(w, "[%-4s] %s: %s\n", check.Status, check.Subject, Redact(check.Detail))
+	}
+	return failed
+}
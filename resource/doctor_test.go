@@ -0,0 +1,53 @@
+package resource
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunDoctor(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com", "example.org")
+	f.dnssec["zone-2"] = "disabled"
+
+	checks := runDoctor(cloudflareCredentials(), nil)
+
+	var buf bytes.Buffer
+	if failed := writeDoctorReport(&buf, checks); failed != 0 {
+		t.Errorf("Expected no failures, got %d:\n%s", failed, buf.String())
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"[OK  ] token: API token token-1 is active",
+		"[OK  ] zones: 2 zone(s) visible",
+		"[OK  ] example.com: TLSA records can be read and probably written (write probe)",
+		"[OK  ] example.com: DNSSEC is active",
+		"[WARN] example.org: DNSSEC is disabled",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestRunDoctor_Failures(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.readOnly = true
+
+	checks := runDoctor(cloudflareCredentials(), []string{"example.com", "example.net"})
+
+	var buf bytes.Buffer
+	if failed := writeDoctorReport(&buf, checks); failed != 2 {
+		t.Errorf("Expected 2 failures, got %d:\n%s", failed, buf.String())
+	}
+	if !strings.Contains(buf.String(), "[FAIL] example.net: no zone visible to the token") {
+		t.Errorf("Expected invisible zone failure, got:\n%s", buf.String())
+	}
+
+	t.Setenv("TOKEN", "wrong-token")
+	checks = runDoctor(cloudflareCredentials(), nil)
+	if len(checks) != 1 || checks[0].Status != doctorFail || checks[0].Subject != "token" {
+		t.Errorf("Expected a single token failure, got %+v", checks)
+	}
+}
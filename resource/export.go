@@ -0,0 +1,165 @@
+package resource
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// exportedRecord is the output form of a TLSA record for export. Name is
+// fully qualified, without the trailing dot.
+type exportedRecord struct {
+	Zone         string `json:"zone" yaml:"zone"`
+	Name         string `json:"name" yaml:"name"`
+	TTL          int    `json:"ttl" yaml:"ttl"`
+	Usage        int    `json:"usage" yaml:"usage"`
+	Selector     int    `json:"selector" yaml:"selector"`
+	MatchingType int    `json:"matching_type" yaml:"matching_type"`
+	Certificate  string `json:"certificate" yaml:"certificate"`
+}
+
+func ResourceExport(cmd *cobra.Command, args []string) error {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	if format != "bind" && format != "json" && format != "yaml" {
+		return fmt.Errorf("unknown export format %q, must be one of bind, json, yaml", format)
+	}
+
+	fromProvider, err := cmd.Flags().GetBool("from-provider")
+	if err != nil {
+		return err
+	}
+
+	var records []exportedRecord
+	if fromProvider {
+		url, _ := cmd.Flags().GetString("url")
+		subdomain, _ := cmd.Flags().GetString("subdomain")
+		all, _ := cmd.Flags().GetBool("all")
+		if url == "" {
+			return fmt.Errorf("--url is required with --from-provider")
+		}
+		records, err = exportFromProvider(cloudflareCredentials(), url, subdomain, all)
+	} else {
+		var all []tlsaOptions
+		all, err = loadTLSAOptions(cmd)
+		if err == nil {
+			records, err = exportFromCerts(all)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeExport(os.Stdout, records, format)
+}
+
+// exportFromCerts returns the records the options would publish.
+func exportFromCerts(all []tlsaOptions) ([]exportedRecord, error) {
+	var records []exportedRecord
+	for _, opts := range all {
+		reqs, err := opts.records("Created")
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range reqs {
+			records = append(records, exportedRecord{
+				Zone:         strings.ToLower(opts.URL),
+				Name:         strings.ToLower(req.Name + "." + opts.URL),
+				TTL:          req.Ttl,
+				Usage:        req.Data.Usage,
+				Selector:     req.Data.Selector,
+				MatchingType: req.Data.Matchingtype,
+				Certificate:  req.Data.Certificate,
+			})
+		}
+	}
+	return records, nil
+}
+
+// exportFromProvider returns the managed TLSA records in the zone of url, or
+// every TLSA record with all.
+func exportFromProvider(auth cloudflareAuth, url, subdomain string, all bool) ([]exportedRecord, error) {
+	listed, err := listRecords(auth, url, subdomain)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []exportedRecord
+	for _, record := range listed {
+		if !all && !record.Managed {
+			continue
+		}
+		records = append(records, exportedRecord{
+			Zone:         strings.ToLower(url),
+			Name:         strings.ToLower(record.Name),
+			TTL:          record.TTL,
+			Usage:        record.Usage,
+			Selector:     record.Selector,
+			MatchingType: record.MatchingType,
+			Certificate:  record.Certificate,
+		})
+	}
+	return records, nil
+}
+
+// tlsaRR returns the record as a TLSA resource record named relative to its
+// zone. The apex and names outside the zone keep their fully qualified name.
+func (r exportedRecord) tlsaRR() *dns.TLSA {
+	name := dns.Fqdn(r.Name)
+	if strings.HasSuffix(r.Name, "."+r.Zone) {
+		name = strings.TrimSuffix(r.Name, "."+r.Zone)
+	}
+	ttl := r.TTL
+	if ttl == ttlAuto {
+		ttl = autoTTLSeconds
+	}
+	return &dns.TLSA{
+		Hdr:          dns.RR_Header{Name: name, Rrtype: dns.TypeTLSA, Class: dns.ClassINET, Ttl: uint32(ttl)},
+		Usage:        uint8(r.Usage),
+		Selector:     uint8(r.Selector),
+		MatchingType: uint8(r.MatchingType),
+		Certificate:  strings.ToLower(r.Certificate),
+	}
+}
+
+func writeExport(w io.Writer, records []exportedRecord, format string) error {
+	slices.SortStableFunc(records, func(a, b exportedRecord) int {
+		return cmp.Or(cmp.Compare(a.Zone, b.Zone), cmp.Compare(a.Name, b.Name), cmp.Compare(b.Usage, a.Usage))
+	})
+	if records == nil {
+		records = []exportedRecord{}
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "yaml":
+		return yaml.NewEncoder(w).Encode(records)
+	case "bind", "":
+		fmt.Fprintln(w, "; TLSA records exported by gotlsaflare")
+		zone := ""
+		for _, record := range records {
+			if record.Zone != zone {
+				zone = record.Zone
+				fmt.Fprintf(w, "$ORIGIN %s\n", dns.Fqdn(zone))
+			}
+			if _, err := fmt.Fprintln(w, record.tlsaRR().String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown export format %q, must be one of bind, json, yaml", format)
+}
@@ -0,0 +1,108 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportFromCerts(t *testing.T) {
+	opts := syncTestOptions(t)
+	opts.TTL = ttlAuto
+
+	records, err := exportFromCerts([]tlsaOptions{opts})
+	if err != nil {
+		t.Fatalf("exportFromCerts() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Zone != "example.com" || records[0].Name != "_25._tcp.mail.example.com" || records[0].TTL != ttlAuto || records[1].Name != "_587._tcp.mail.example.com" {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+
+	opts.Cert = "/nonexistent/cert.pem"
+	if _, err := exportFromCerts([]tlsaOptions{opts}); err == nil {
+		t.Error("Expected error for missing certificate")
+	}
+}
+
+func TestExportFromProvider(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", managedComment)
+	f.addRecord("zone-1", "_443._tcp.www.example.com", 3, 1, 1, "bbbb", "")
+
+	managed, err := exportFromProvider(cloudflareCredentials(), "example.com", "", false)
+	if err != nil {
+		t.Fatalf("exportFromProvider() error = %v", err)
+	}
+	if len(managed) != 1 || managed[0].Certificate != "aaaa" {
+		t.Errorf("Expected only the managed record, got %+v", managed)
+	}
+
+	all, err := exportFromProvider(cloudflareCredentials(), "example.com", "", true)
+	if err != nil {
+		t.Fatalf("exportFromProvider() error = %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("Expected 2 records with all, got %d", len(all))
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	certificate := strings.Repeat("AB", 32)
+	records := []exportedRecord{
+		{Zone: "example.com", Name: "_587._tcp.mail.example.com", TTL: 3600, Usage: 3, Selector: 1, MatchingType: 1, Certificate: certificate},
+		{Zone: "example.com", Name: "_25._tcp.mail.example.com", TTL: ttlAuto, Usage: 3, Selector: 1, MatchingType: 1, Certificate: certificate},
+		{Zone: "example.com", Name: "example.com", TTL: 3600, Usage: 2, Selector: 0, MatchingType: 1, Certificate: certificate},
+	}
+
+	var buf bytes.Buffer
+	if err := writeExport(&buf, records, "bind"); err != nil {
+		t.Fatalf("writeExport(bind) error = %v", err)
+	}
+	want := "_25._tcp.mail\t300\tIN\tTLSA\t3 1 1 " + strings.ToLower(certificate)
+	if !strings.Contains(buf.String(), "$ORIGIN example.com.\n") || !strings.Contains(buf.String(), want) {
+		t.Errorf("Unexpected bind output:\n%s", buf.String())
+	}
+
+	// Every line must parse back as a zone file
+	parser := dns.NewZoneParser(bufio.NewReader(&buf), "", "")
+	var names []string
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		names = append(names, rr.Header().Name)
+	}
+	if err := parser.Err(); err != nil {
+		t.Fatalf("Exported zone does not parse: %v", err)
+	}
+	if strings.Join(names, " ") != "_25._tcp.mail.example.com. _587._tcp.mail.example.com. example.com." {
+		t.Errorf("Unexpected owner names: %v", names)
+	}
+
+	buf.Reset()
+	if err := writeExport(&buf, records, "json"); err != nil {
+		t.Fatalf("writeExport(json) error = %v", err)
+	}
+	var fromJSON []exportedRecord
+	if err := json.Unmarshal(buf.Bytes(), &fromJSON); err != nil || len(fromJSON) != 3 {
+		t.Errorf("Unexpected JSON output (%v):\n%s", err, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeExport(&buf, records, "yaml"); err != nil {
+		t.Fatalf("writeExport(yaml) error = %v", err)
+	}
+	var fromYAML []exportedRecord
+	if err := yaml.Unmarshal(buf.Bytes(), &fromYAML); err != nil || fromYAML[0].Name != "_25._tcp.mail.example.com" {
+		t.Errorf("Unexpected YAML output (%v):\n%s", err, buf.String())
+	}
+
+	if err := writeExport(&buf, records, "csv"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
@@ -1,21 +1,18 @@
 package resource
 
-import (
-	"encoding/json"
-	"log"
-	"os"
-	"time"
-)
-
-func genCloudflareReq(certfile string, port string, protocol string, subdomain string, cu string, usage int, selector int, matchingType int) string {
-	currentTime := time.Now()
+import "time"
 
-	eeHash, caHash := getHash(certfile, selector, matchingType)
-	certificate := eeHash
-	if usage == 2 {
-		certificate = caHash
-	}
+const (
+	defaultTTL = 3600
+	// ttlAuto is how Cloudflare stores an automatic TTL, which resolvers
+	// see as autoTTLSeconds.
+	ttlAuto        = 1
+	autoTTLSeconds = 300
+)
 
+// buildCloudflareReq returns the Cloudflare request for a TLSA record whose
+// certificate association data is already computed.
+func buildCloudflareReq(port string, protocol string, subdomain string, cu string, usage int, selector int, matchingType int, certificate string) JSONRequest {
 	data := Data{
 		Usage:        usage,
 		Selector:     selector,
@@ -23,21 +20,30 @@ func genCloudflareReq(certfile string, port string, protocol string, subdomain s
 		Certificate:  certificate,
 	}
 
-	jsonRequest := JSONRequest{
+	return JSONRequest{
 		Type:     "TLSA",
-		Name:     "_" + port + "._" + protocol + "." + subdomain,
+		Name:     tlsaOwner(port, protocol, subdomain),
 		Data:     data,
-		Ttl:      3600,
+		Ttl:      defaultTTL,
 		Priority: 10,
 		Proxied:  false,
-		Comment:  cu + " by GoTLSAFlare - " + currentTime.Format("2006-01-02 15:04:05"),
+		Comment:  recordComment(cu),
 	}
+}
 
-	byteArray, err := json.MarshalIndent(jsonRequest, "", "  ")
-
-	if err != nil {
-		log.Println(err)
-		os.Exit(1)
+// tlsaOwner returns the TLSA owner name of a service relative to the zone,
+// at the zone apex when subdomain is empty or "@".
+func tlsaOwner(port, protocol, subdomain string) string {
+	owner := "_" + port + "._" + protocol
+	if isApex(subdomain) {
+		return owner
 	}
-	return string(byteArray)
+	return owner + "." + subdomain
+}
+
+// recordComment returns the comment stored on records written by gotlsaflare.
+// It always contains managedMarker so later runs recognise the record.
+func recordComment(cu string) string {
+	currentTime := time.Now()
+	return cu + " " + managedMarker + " - " + currentTime.Format("2006-01-02 15:04:05")
 }
@@ -16,7 +16,7 @@ import (
 	"time"
 )
 
-// Helper function to generate a test certificate for genCloudflareReq tests
+// Helper function to generate a test certificate for Cloudflare request tests
 func generateTestCertForReq(t *testing.T) string {
 	t.Helper()
 
@@ -67,16 +67,27 @@ func generateTestCertForReq(t *testing.T) string {
 	return certPath
 }
 
-func TestGenCloudflareReq_DANEEE_SHA256(t *testing.T) {
-	certPath := generateTestCertForReq(t)
-
-	result := genCloudflareReq(certPath, "25", "tcp", "mail", "Created", 3, 1, 1)
+// testCloudflareReq returns the record tlsaOptions.record builds for the
+// certificate at certPath, with the hash computeHash returns for the usage.
+func testCloudflareReq(t *testing.T, certPath string, port string, subdomain string, cu string, usage int, selector int, matchingType int) JSONRequest {
+	t.Helper()
 
-	// Parse the JSON result
-	var jsonReq JSONRequest
-	if err := json.Unmarshal([]byte(result), &jsonReq); err != nil {
-		t.Fatalf("Failed to parse JSON: %v", err)
+	eeHash, caHash, err := computeHash(certPath, selector, matchingType)
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
+	certificate := eeHash
+	if usage == 2 {
+		certificate = caHash
 	}
+	opts := tlsaOptions{URL: "example.com", Subdomain: subdomain, MatchingType: matchingType}
+	return opts.record(tlsaService{Port: port, Protocol: "tcp"}, cu, usage, selector, certificate)
+}
+
+func TestBuildCloudflareReq_DANEEE_SHA256(t *testing.T) {
+	certPath := generateTestCertForReq(t)
+
+	jsonReq := testCloudflareReq(t, certPath, "25", "mail", "Created", 3, 1, 1)
 
 	// Verify the structure
 	if jsonReq.Type != "TLSA" {
@@ -125,15 +136,10 @@ func TestGenCloudflareReq_DANEEE_SHA256(t *testing.T) {
 	}
 }
 
-func TestGenCloudflareReq_DANEEE_SHA512(t *testing.T) {
+func TestBuildCloudflareReq_DANEEE_SHA512(t *testing.T) {
 	certPath := generateTestCertForReq(t)
 
-	result := genCloudflareReq(certPath, "443", "tcp", "www", "Updated", 3, 1, 2)
-
-	var jsonReq JSONRequest
-	if err := json.Unmarshal([]byte(result), &jsonReq); err != nil {
-		t.Fatalf("Failed to parse JSON: %v", err)
-	}
+	jsonReq := testCloudflareReq(t, certPath, "443", "www", "Updated", 3, 1, 2)
 
 	if jsonReq.Data.Matchingtype != 2 {
 		t.Errorf("Expected Matchingtype 2 (SHA512), got %d", jsonReq.Data.Matchingtype)
@@ -148,7 +154,7 @@ func TestGenCloudflareReq_DANEEE_SHA512(t *testing.T) {
 	}
 }
 
-func TestGenCloudflareReq_DANETA_SHA256(t *testing.T) {
+func TestBuildCloudflareReq_DANETA_SHA256(t *testing.T) {
 	// Create a certificate chain (EE + CA)
 	privateKey1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	privateKey2, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -199,12 +205,7 @@ func TestGenCloudflareReq_DANETA_SHA256(t *testing.T) {
 	pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: caBytes})
 
 	// Test DANE-TA (usage 2) with selector 0
-	result := genCloudflareReq(certPath, "25", "tcp", "mail", "Created", 2, 0, 1)
-
-	var jsonReq JSONRequest
-	if err := json.Unmarshal([]byte(result), &jsonReq); err != nil {
-		t.Fatalf("Failed to parse JSON: %v", err)
-	}
+	jsonReq := testCloudflareReq(t, certPath, "25", "mail", "Created", 2, 0, 1)
 
 	if jsonReq.Data.Usage != 2 {
 		t.Errorf("Expected Usage 2 (DANE-TA), got %d", jsonReq.Data.Usage)
@@ -219,7 +220,7 @@ func TestGenCloudflareReq_DANETA_SHA256(t *testing.T) {
 	}
 }
 
-func TestGenCloudflareReq_DifferentPorts(t *testing.T) {
+func TestBuildCloudflareReq_DifferentPorts(t *testing.T) {
 	certPath := generateTestCertForReq(t)
 
 	testCases := []struct {
@@ -236,12 +237,7 @@ func TestGenCloudflareReq_DifferentPorts(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.port, func(t *testing.T) {
-			result := genCloudflareReq(certPath, tc.port, "tcp", tc.subdomain, "Created", 3, 1, 1)
-
-			var jsonReq JSONRequest
-			if err := json.Unmarshal([]byte(result), &jsonReq); err != nil {
-				t.Fatalf("Failed to parse JSON: %v", err)
-			}
+			jsonReq := testCloudflareReq(t, certPath, tc.port, tc.subdomain, "Created", 3, 1, 1)
 
 			if jsonReq.Name != tc.expectedName {
 				t.Errorf("Expected Name '%s', got '%s'", tc.expectedName, jsonReq.Name)
@@ -250,14 +246,17 @@ func TestGenCloudflareReq_DifferentPorts(t *testing.T) {
 	}
 }
 
-func TestGenCloudflareReq_JSONFormat(t *testing.T) {
+func TestBuildCloudflareReq_JSONFormat(t *testing.T) {
 	certPath := generateTestCertForReq(t)
 
-	result := genCloudflareReq(certPath, "25", "tcp", "mail", "Created", 3, 1, 1)
+	result, err := json.Marshal(testCloudflareReq(t, certPath, "25", "mail", "Created", 3, 1, 1))
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
 
 	// Verify it's valid JSON
 	var jsonReq map[string]interface{}
-	if err := json.Unmarshal([]byte(result), &jsonReq); err != nil {
+	if err := json.Unmarshal(result, &jsonReq); err != nil {
 		t.Fatalf("Result is not valid JSON: %v", err)
 	}
 
@@ -283,15 +282,10 @@ func TestGenCloudflareReq_JSONFormat(t *testing.T) {
 	}
 }
 
-func TestGenCloudflareReq_Selector0_FullCert(t *testing.T) {
+func TestBuildCloudflareReq_Selector0_FullCert(t *testing.T) {
 	certPath := generateTestCertForReq(t)
 
-	result := genCloudflareReq(certPath, "25", "tcp", "mail", "Created", 3, 0, 1)
-
-	var jsonReq JSONRequest
-	if err := json.Unmarshal([]byte(result), &jsonReq); err != nil {
-		t.Fatalf("Failed to parse JSON: %v", err)
-	}
+	jsonReq := testCloudflareReq(t, certPath, "25", "mail", "Created", 3, 0, 1)
 
 	if jsonReq.Data.Selector != 0 {
 		t.Errorf("Expected Selector 0 (full cert), got %d", jsonReq.Data.Selector)
@@ -303,7 +297,7 @@ func TestGenCloudflareReq_Selector0_FullCert(t *testing.T) {
 	}
 }
 
-func TestGenCloudflareReq_CommentFormat(t *testing.T) {
+func TestBuildCloudflareReq_CommentFormat(t *testing.T) {
 	certPath := generateTestCertForReq(t)
 
 	testCases := []struct {
@@ -316,12 +310,7 @@ func TestGenCloudflareReq_CommentFormat(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.cu, func(t *testing.T) {
-			result := genCloudflareReq(certPath, "25", "tcp", "mail", tc.cu, 3, 1, 1)
-
-			var jsonReq JSONRequest
-			if err := json.Unmarshal([]byte(result), &jsonReq); err != nil {
-				t.Fatalf("Failed to parse JSON: %v", err)
-			}
+			jsonReq := testCloudflareReq(t, certPath, "25", "mail", tc.cu, 3, 1, 1)
 
 			if !strings.HasPrefix(jsonReq.Comment, tc.expected) {
 				t.Errorf("Expected comment to start with '%s', got '%s'", tc.expected, jsonReq.Comment)
@@ -6,24 +6,28 @@ import (
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
-	"log"
+	"fmt"
 	"os"
 )
 
-func getHash(certfile string, selector int, matchingType int) (string, string) {
+// computeHash returns the end-entity and CA hashes of the certificate chain in
+// certfile. A file that cannot be read or parsed is an error rather than an
+// exit, since it may be mid-rewrite.
+func computeHash(certfile string, selector int, matchingType int) (string, string, error) {
 	pemContent, err := os.ReadFile(certfile)
 	if err != nil {
-		log.Println(err)
-		os.Exit(1)
+		return "", "", err
 	}
 
 	// Get end-entity certificate (first in chain)
 	block, rest := pem.Decode([]byte(pemContent))
 	if block == nil {
-		log.Println("Failed to parse pem file")
-		os.Exit(1)
+		return "", "", fmt.Errorf("failed to parse pem file %s", certfile)
+	}
+	eeCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse certificate in %s: %v", certfile, err)
 	}
-	eeCert, _ := x509.ParseCertificate(block.Bytes)
 	var eeHash string
 
 	if selector == 0 {
@@ -41,22 +45,33 @@ func getHash(certfile string, selector int, matchingType int) (string, string) {
 		// Hash just the public key
 		if matchingType == 1 {
 			// SHA2-256
-			eeHash = getPublicKeySHA256(eeCert)
+			eeHash, err = getPublicKeySHA256(eeCert)
 		} else if matchingType == 2 {
 			// SHA2-512
-			eeHash = getPublicKeySHA512(eeCert)
+			eeHash, err = getPublicKeySHA512(eeCert)
+		}
+		if err != nil {
+			return "", "", err
 		}
 	}
 
 	// Get CA certificate (last in chain)
 	var caCert *x509.Certificate
+	var caDER []byte
 	var caHash string
 	for len(rest) > 0 {
 		block, rest = pem.Decode(rest)
 		if block == nil {
 			break
 		}
-		caCert, _ = x509.ParseCertificate(block.Bytes)
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		caCert, err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse chain certificate in %s: %v", certfile, err)
+		}
+		caDER = block.Bytes
 	}
 
 	if caCert != nil {
@@ -64,49 +79,45 @@ func getHash(certfile string, selector int, matchingType int) (string, string) {
 			// Hash the entire CA certificate
 			if matchingType == 1 {
 				// SHA2-256
-				sum := sha256.Sum256(block.Bytes)
+				sum := sha256.Sum256(caDER)
 				caHash = hex.EncodeToString(sum[:])
 			} else if matchingType == 2 {
 				// SHA2-512
-				sum := sha512.Sum512(block.Bytes)
+				sum := sha512.Sum512(caDER)
 				caHash = hex.EncodeToString(sum[:])
 			}
 		} else {
 			// Hash just the public key
 			if matchingType == 1 {
 				// SHA2-256
-				caHash = getPublicKeySHA256(caCert)
+				caHash, err = getPublicKeySHA256(caCert)
 			} else if matchingType == 2 {
 				// SHA2-512
-				caHash = getPublicKeySHA512(caCert)
+				caHash, err = getPublicKeySHA512(caCert)
+			}
+			if err != nil {
+				return "", "", err
 			}
 		}
 	}
 
-	return eeHash, caHash
-}
-
-// For backward compatibility
-func getSHA256sum(certfile string, selector int) (string, string) {
-	return getHash(certfile, selector, 1)
+	return eeHash, caHash, nil
 }
 
-func getPublicKeySHA256(cert *x509.Certificate) string {
+func getPublicKeySHA256(cert *x509.Certificate) (string, error) {
 	keyDER, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
 	if err != nil {
-		log.Println(err)
-		os.Exit(1)
+		return "", fmt.Errorf("error encoding public key: %v", err)
 	}
 	sum := sha256.Sum256(keyDER)
-	return hex.EncodeToString(sum[:])
+	return hex.EncodeToString(sum[:]), nil
 }
 
-func getPublicKeySHA512(cert *x509.Certificate) string {
+func getPublicKeySHA512(cert *x509.Certificate) (string, error) {
 	keyDER, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
 	if err != nil {
-		log.Println(err)
-		os.Exit(1)
+		return "", fmt.Errorf("error encoding public key: %v", err)
 	}
 	sum := sha512.Sum512(keyDER)
-	return hex.EncodeToString(sum[:])
+	return hex.EncodeToString(sum[:]), nil
 }
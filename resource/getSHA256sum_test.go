@@ -85,13 +85,16 @@ func writeCertsToPEMFile(t *testing.T, filename string, certs ...*x509.Certifica
 	return certPath
 }
 
-func TestGetHash_SHA256_Selector1(t *testing.T) {
+func TestComputeHash_SHA256_Selector1(t *testing.T) {
 	// Generate test certificate
 	cert, _ := generateTestCertificate(t, false)
 	certPath := writeCertsToPEMFile(t, "test_cert.pem", cert)
 
 	// Test with selector 1 (public key) and matching type 1 (SHA2-256)
-	eeHash, caHash := getHash(certPath, 1, 1)
+	eeHash, caHash, err := computeHash(certPath, 1, 1)
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
 
 	if eeHash == "" {
 		t.Error("Expected non-empty EE hash")
@@ -106,13 +109,16 @@ func TestGetHash_SHA256_Selector1(t *testing.T) {
 	}
 }
 
-func TestGetHash_SHA256_Selector0(t *testing.T) {
+func TestComputeHash_SHA256_Selector0(t *testing.T) {
 	// Generate test certificate
 	cert, _ := generateTestCertificate(t, false)
 	certPath := writeCertsToPEMFile(t, "test_cert.pem", cert)
 
 	// Test with selector 0 (full certificate) and matching type 1 (SHA2-256)
-	eeHash, caHash := getHash(certPath, 0, 1)
+	eeHash, caHash, err := computeHash(certPath, 0, 1)
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
 
 	if eeHash == "" {
 		t.Error("Expected non-empty EE hash")
@@ -127,13 +133,16 @@ func TestGetHash_SHA256_Selector0(t *testing.T) {
 	}
 }
 
-func TestGetHash_SHA512_Selector1(t *testing.T) {
+func TestComputeHash_SHA512_Selector1(t *testing.T) {
 	// Generate test certificate
 	cert, _ := generateTestCertificate(t, false)
 	certPath := writeCertsToPEMFile(t, "test_cert.pem", cert)
 
 	// Test with selector 1 (public key) and matching type 2 (SHA2-512)
-	eeHash, caHash := getHash(certPath, 1, 2)
+	eeHash, caHash, err := computeHash(certPath, 1, 2)
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
 
 	if eeHash == "" {
 		t.Error("Expected non-empty EE hash")
@@ -148,13 +157,16 @@ func TestGetHash_SHA512_Selector1(t *testing.T) {
 	}
 }
 
-func TestGetHash_SHA512_Selector0(t *testing.T) {
+func TestComputeHash_SHA512_Selector0(t *testing.T) {
 	// Generate test certificate
 	cert, _ := generateTestCertificate(t, false)
 	certPath := writeCertsToPEMFile(t, "test_cert.pem", cert)
 
 	// Test with selector 0 (full certificate) and matching type 2 (SHA2-512)
-	eeHash, caHash := getHash(certPath, 0, 2)
+	eeHash, caHash, err := computeHash(certPath, 0, 2)
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
 
 	if eeHash == "" {
 		t.Error("Expected non-empty EE hash")
@@ -169,7 +181,7 @@ func TestGetHash_SHA512_Selector0(t *testing.T) {
 	}
 }
 
-func TestGetHash_WithCAChain_SHA256(t *testing.T) {
+func TestComputeHash_WithCAChain_SHA256(t *testing.T) {
 	// Generate test certificates (end-entity and CA)
 	eeCert, _ := generateTestCertificate(t, false)
 	caCert, _ := generateTestCertificate(t, true)
@@ -177,7 +189,10 @@ func TestGetHash_WithCAChain_SHA256(t *testing.T) {
 	certPath := writeCertsToPEMFile(t, "test_fullchain.pem", eeCert, caCert)
 
 	// Test with selector 1 (public key) and matching type 1 (SHA2-256)
-	eeHash, caHash := getHash(certPath, 1, 1)
+	eeHash, caHash, err := computeHash(certPath, 1, 1)
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
 
 	if eeHash == "" {
 		t.Error("Expected non-empty EE hash")
@@ -201,7 +216,7 @@ func TestGetHash_WithCAChain_SHA256(t *testing.T) {
 	}
 }
 
-func TestGetHash_WithCAChain_SHA512(t *testing.T) {
+func TestComputeHash_WithCAChain_SHA512(t *testing.T) {
 	// Generate test certificates (end-entity and CA)
 	eeCert, _ := generateTestCertificate(t, false)
 	caCert, _ := generateTestCertificate(t, true)
@@ -209,7 +224,10 @@ func TestGetHash_WithCAChain_SHA512(t *testing.T) {
 	certPath := writeCertsToPEMFile(t, "test_fullchain.pem", eeCert, caCert)
 
 	// Test with selector 1 (public key) and matching type 2 (SHA2-512)
-	eeHash, caHash := getHash(certPath, 1, 2)
+	eeHash, caHash, err := computeHash(certPath, 1, 2)
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
 
 	if eeHash == "" {
 		t.Error("Expected non-empty EE hash")
@@ -233,42 +251,13 @@ func TestGetHash_WithCAChain_SHA512(t *testing.T) {
 	}
 }
 
-func TestGetSHA256sum_BackwardCompatibility(t *testing.T) {
-	// Generate test certificate
-	cert, _ := generateTestCertificate(t, false)
-	certPath := writeCertsToPEMFile(t, "test_cert.pem", cert)
-
-	// Test backward compatibility function
-	eeHash, caHash := getSHA256sum(certPath, 1)
-
-	if eeHash == "" {
-		t.Error("Expected non-empty EE hash")
-	}
-
-	if len(eeHash) != 64 {
-		t.Errorf("Expected hash length 64, got %d", len(eeHash))
-	}
-
-	if caHash != "" {
-		t.Error("Expected empty CA hash for single certificate")
-	}
-
-	// Verify it produces same result as getHash with matching type 1
-	eeHash2, caHash2 := getHash(certPath, 1, 1)
-
-	if eeHash != eeHash2 {
-		t.Error("getSHA256sum should produce same result as getHash with matching type 1")
-	}
-
-	if caHash != caHash2 {
-		t.Error("getSHA256sum should produce same CA hash as getHash with matching type 1")
-	}
-}
-
 func TestGetPublicKeySHA256(t *testing.T) {
 	cert, _ := generateTestCertificate(t, false)
 
-	hash := getPublicKeySHA256(cert)
+	hash, err := getPublicKeySHA256(cert)
+	if err != nil {
+		t.Fatalf("getPublicKeySHA256() error = %v", err)
+	}
 
 	if hash == "" {
 		t.Error("Expected non-empty hash")
@@ -282,7 +271,10 @@ func TestGetPublicKeySHA256(t *testing.T) {
 func TestGetPublicKeySHA512(t *testing.T) {
 	cert, _ := generateTestCertificate(t, false)
 
-	hash := getPublicKeySHA512(cert)
+	hash, err := getPublicKeySHA512(cert)
+	if err != nil {
+		t.Fatalf("getPublicKeySHA512() error = %v", err)
+	}
 
 	if hash == "" {
 		t.Error("Expected non-empty hash")
@@ -293,14 +285,20 @@ func TestGetPublicKeySHA512(t *testing.T) {
 	}
 }
 
-func TestGetHash_DifferentSelectorResults(t *testing.T) {
+func TestComputeHash_DifferentSelectorResults(t *testing.T) {
 	// Generate test certificate
 	cert, _ := generateTestCertificate(t, false)
 	certPath := writeCertsToPEMFile(t, "test_cert.pem", cert)
 
 	// Get hashes with different selectors
-	hash0, _ := getHash(certPath, 0, 1) // Full certificate
-	hash1, _ := getHash(certPath, 1, 1) // Public key only
+	hash0, _, err := computeHash(certPath, 0, 1) // Full certificate
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
+	hash1, _, err := computeHash(certPath, 1, 1) // Public key only
+	if err != nil {
+		t.Fatalf("computeHash() error = %v", err)
+	}
 
 	// The hashes should be different
 	if hash0 == hash1 {
@@ -312,8 +310,23 @@ func TestGetHash_DifferentSelectorResults(t *testing.T) {
 	}
 }
 
-func TestGetHash_InvalidFile(t *testing.T) {
-	// This test will cause the function to exit, so we skip it in normal test runs
-	// In a production environment, you might want to refactor to return errors instead of os.Exit
-	t.Skip("Skipping test that causes os.Exit")
+func TestComputeHash_InvalidFile(t *testing.T) {
+	if _, _, err := computeHash(filepath.Join(t.TempDir(), "missing.pem"), 1, 1); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	// A truncated PEM file, as seen mid-write during renewal
+	cert, _ := generateTestCertificate(t, false)
+	certPath := writeCertsToPEMFile(t, "test_cert.pem", cert)
+	content, err := os.ReadFile(certPath)
+	if err != nil {
+		t.Fatalf("Failed to read certificate: %v", err)
+	}
+	if err := os.WriteFile(certPath, content[:len(content)/2], 0o644); err != nil {
+		t.Fatalf("Failed to truncate certificate: %v", err)
+	}
+
+	if _, _, err := computeHash(certPath, 1, 1); err == nil {
+		t.Error("Expected error for truncated PEM file")
+	}
 }
@@ -0,0 +1,106 @@
+package resource
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func ResourceImport(cmd *cobra.Command, args []string) error {
+	all, err := loadTLSAOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	auth := cloudflareCredentials()
+	plan := func() ([]recordChange, error) {
+		return planImport(all, auth)
+	}
+
+	if stop, err := handleDryRun(cmd, "import", plan); stop {
+		return err
+	}
+
+	changes, err := plan()
+	if err != nil {
+		return err
+	}
+
+	printPlan(os.Stdout, changes)
+	if err := preflight(cmd, auth, changeNames(changes)); err != nil {
+		return err
+	}
+	return applyChanges(changes, auth)
+}
+
+// planImport adopts existing TLSA records that were not written by
+// gotlsaflare. A record is adopted when it matches the certificate exactly:
+// its comment is rewritten to carry managedMarker, and the tags and TTL are
+// set if given. Its data is never changed. Records that do not match the
+// certificate are left alone, so a record describing some other key is never
+// taken over.
+func planImport(all []tlsaOptions, auth cloudflareAuth) ([]recordChange, error) {
+	for _, opts := range all {
+		if _, _, err := opts.hashes(); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot, err := newProviderSnapshot(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []recordChange
+	for _, opts := range all {
+		zone, err := snapshot.zoneFor(opts.host())
+		if err != nil {
+			return nil, err
+		}
+
+		wanted, err := opts.records("Imported")
+		if err != nil {
+			return nil, err
+		}
+		for _, want := range wanted {
+			name := strings.ToLower(want.Name + "." + opts.URL)
+			existing, err := snapshot.recordsAt(zone.ID, name)
+			if err != nil {
+				return nil, err
+			}
+
+			found := false
+			for _, have := range existing {
+				if have.Data.Usage != want.Data.Usage {
+					continue
+				}
+				found = true
+				if !sameTLSAData(want, have) {
+					slog.Warn("Not importing TLSA record that does not match the certificate", "name", name,
+						"data", formatTLSAData(have.Data.Usage, have.Data.Selector, have.Data.MatchingType, have.Data.Certificate))
+					continue
+				}
+
+				old := have
+				if isManaged(have) {
+					changes = append(changes, recordChange{Action: actionNoop, ZoneID: zone.ID, Name: name, Old: &old})
+					continue
+				}
+				next := want
+				if opts.TTL == 0 {
+					next.Ttl = have.TTL
+				}
+				if len(opts.Tags) == 0 {
+					next.Tags = have.Tags
+				}
+				changes = append(changes, recordChange{Action: actionUpdate, ZoneID: zone.ID, Name: name, Old: &old, New: &next})
+			}
+			if !found {
+				slog.Warn("No TLSA record to import", "name", name, "usage", want.Data.Usage)
+			}
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPlanImport(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = append(opts.Services, tlsaService{Port: "465", Protocol: "tcp"})
+	want := mustRecords(t, opts, "Imported")
+
+	// Port 25 was made by hand, 587 is already managed and 465 holds some
+	// other key
+	handMade := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, want[0].Data.Certificate, "added by ops")
+	f.addRecord("zone-1", "_587._tcp.mail.example.com", 3, 1, 1, want[1].Data.Certificate, managedComment)
+	f.addRecord("zone-1", "_465._tcp.mail.example.com", 3, 1, 1, "ffff", "added by ops")
+
+	changes, err := planImport([]tlsaOptions{opts}, cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("planImport() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionUpdate] != 1 || counts[actionNoop] != 1 || len(changes) != 2 {
+		t.Fatalf("Expected 1 adoption and 1 unchanged record, got %v", counts)
+	}
+
+	adopt := changes[0]
+	if adopt.Action != actionUpdate || adopt.Old.ID != handMade.ID {
+		t.Fatalf("Expected the hand-made record to be adopted, got %+v", adopt)
+	}
+	if !strings.Contains(adopt.New.Comment, "Imported "+managedMarker) || adopt.New.Ttl != handMade.TTL || !sameTLSAData(*adopt.New, *adopt.Old) {
+		t.Errorf("Expected only the comment to change, got %+v", adopt.New)
+	}
+
+	var buf bytes.Buffer
+	printPlan(&buf, changes)
+	if !strings.Contains(buf.String(), "# _25._tcp.mail.example.com will be adopted") || !strings.Contains(buf.String(), `comment: "added by ops" -> "Imported by GoTLSAFlare`) {
+		t.Errorf("Unexpected plan output:\n%s", buf.String())
+	}
+
+	if err := applyChanges(changes, cloudflareCredentials()); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	for _, record := range f.zoneRecords("zone-1") {
+		if managed := isManaged(record); managed == (record.Data.Certificate == "ffff") {
+			t.Errorf("Unexpected managed state %v for %s", managed, record.Name)
+		}
+	}
+
+	// Once adopted, sync manages the records without changes
+	opts.TTL = handMade.TTL
+	changes, err = planSync([]tlsaOptions{opts}, cloudflareCredentials(), false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionNoop] != 2 || counts[actionCreate] != 1 {
+		t.Errorf("Expected sync to leave adopted records alone, got %v", counts)
+	}
+}
+
+func TestPlanImport_SetsRequestedSettings(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = opts.Services[:1]
+	opts.TTL = 300
+	opts.Tags = []string{"owner:mail"}
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, mustRecords(t, opts, "Imported")[0].Data.Certificate, "")
+
+	changes, err := planImport([]tlsaOptions{opts}, cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("planImport() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].New.Ttl != 300 || len(changes[0].New.Tags) != 1 {
+		t.Errorf("Expected the TTL and tags to be set, got %+v", changes)
+	}
+}
@@ -0,0 +1,423 @@
+package resource
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// inventoryRow is one host of an inventory file, a CSV file with a header
+// naming its columns:
+//
+//	zone,subdomain,services,usages,cert
+//	example.com,mail,25 465 587,dane-ee dane-ta,/etc/ssl/mail.pem
+//	example.org,xmpp,5269/tcp,,/etc/ssl/xmpp.pem
+//
+// services and usages are lists separated by spaces or semicolons. A service
+// is a port with an optional /proto, and usages default to dane-ee. A
+// subdomain of @ is the zone apex. Lines starting with # are skipped.
+type inventoryRow struct {
+	line int
+	opts tlsaOptions
+}
+
+// inventoryResult is the outcome of one inventory row.
+type inventoryResult struct {
+	Line    int    `json:"line"`
+	Host    string `json:"host"`
+	Action  string `json:"action"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// inventoryDocument is written by --output json: the record results of
+// create and update, plus one result per row.
+type inventoryDocument struct {
+	resultDocument
+	Rows []inventoryResult `json:"rows"`
+}
+
+var inventoryColumns = []string{"zone", "subdomain", "services", "usages", "cert"}
+
+func ResourceBatch(cmd *cobra.Command, args []string) error {
+	path, err := cmd.Flags().GetString("inventory")
+	if err != nil {
+		return err
+	}
+	rows, err := loadInventory(path)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if err := applyRecordFlags(cmd, &rows[i].opts); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, rows[i].line, err)
+		}
+	}
+
+	rollover, err := cmd.Flags().GetBool("rollover")
+	if err != nil {
+		return err
+	}
+
+	concurrency, err := cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	if stop, err := handleDryRun(cmd, "batch", func() ([]recordChange, error) {
+		return planInventory(rows, cloudflareCredentials(), rollover)
+	}); stop {
+		return err
+	}
+
+	output, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := configureNotifiers(cmd); err != nil {
+		return err
+	}
+
+	var all []tlsaOptions
+	for _, row := range rows {
+		all = append(all, row.opts)
+	}
+	if err := preflight(cmd, cloudflareCredentials(), optionHosts(all)); err != nil {
+		return writeInventoryResults(os.Stdout, output, nil, nil, err)
+	}
+
+	summary, results, err := runInventory(rows, rollover, concurrency)
+	return writeInventoryResults(os.Stdout, output, summary, results, err)
+}
+
+// loadInventory reads and validates every row of an inventory file. Rows are
+// checked like config file records, and all problems are reported at once.
+func loadInventory(path string) ([]inventoryRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading inventory: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := parseInventory(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inventory %s: %v", path, err)
+	}
+	return rows, nil
+}
+
+func parseInventory(r io.Reader) ([]inventoryRow, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing header line")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(inventoryColumns, name) {
+			return nil, fmt.Errorf("unknown column %q, must be one of %s", name, strings.Join(inventoryColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"zone", "subdomain", "services", "cert"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var rows []inventoryRow
+	var problems []string
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		record := ConfigRecord{
+			Zone:     field("zone"),
+			Hostname: field("subdomain"),
+			Cert:     field("cert"),
+			Usages:   splitInventoryList(field("usages")),
+		}
+		var rowProblems []string
+		for _, svc := range splitInventoryList(field("services")) {
+			service, err := parseInventoryService(svc)
+			if err != nil {
+				rowProblems = append(rowProblems, err.Error())
+				continue
+			}
+			record.Services = append(record.Services, service)
+		}
+		rowProblems = append(rowProblems, record.problems()...)
+		if len(rowProblems) > 0 {
+			for _, problem := range rowProblems {
+				problems = append(problems, fmt.Sprintf("line %d: %s", line, problem))
+			}
+			continue
+		}
+
+		all, err := (&Config{Records: []ConfigRecord{record}}).options()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		rows = append(rows, inventoryRow{line: line, opts: all[0]})
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("\n  %s", strings.Join(problems, "\n  "))
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("at least one row is required")
+	}
+	return rows, nil
+}
+
+// splitInventoryList splits a services or usages cell on spaces and
+// semicolons.
+func splitInventoryList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == ';'
+	})
+}
+
+// parseInventoryService parses a port with an optional protocol, as 25 or
+// 5269/tcp.
+func parseInventoryService(value string) (ConfigService, error) {
+	port, proto, _ := strings.Cut(value, "/")
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return ConfigService{}, fmt.Errorf("services: %q is not a port or port/proto", value)
+	}
+	return ConfigService{Port: n, Proto: strings.ToLower(proto)}, nil
+}
+
+// inventoryAction decides how a row is published: rows without any of their
+// records are created, and rows with all of them are updated. Rows with only
+// some records are refused; sync reconciles those.
+func inventoryAction(snapshot *providerSnapshot, opts tlsaOptions, rollover bool) (string, error) {
+	records, err := opts.records("Updated")
+	if err != nil {
+		return "", err
+	}
+	found := 0
+	for _, record := range records {
+		name := strings.ToLower(record.Name + "." + opts.URL)
+		_, old, err := existingRecord(snapshot, name, opts.host(), record.Data.Usage)
+		if err != nil {
+			return "", err
+		}
+		if old != nil {
+			found++
+		}
+	}
+
+	switch {
+	case found == 0:
+		return actionCreate, nil
+	case found < len(records):
+		return "", fmt.Errorf("%d of %d TLSA records exist for %s, use sync to reconcile them", found, len(records), opts.host())
+	case rollover:
+		return actionRollover, nil
+	}
+	return actionUpdate, nil
+}
+
+// planInventory mirrors runInventory, planning each row as create or update.
+func planInventory(rows []inventoryRow, auth cloudflareAuth, rollover bool) ([]recordChange, error) {
+	snapshot, err := newProviderSnapshot(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var creates, updates []tlsaOptions
+	for _, row := range rows {
+		action, err := inventoryAction(snapshot, row.opts, rollover)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", row.line, err)
+		}
+		if action == actionCreate {
+			creates = append(creates, row.opts)
+		} else {
+			updates = append(updates, row.opts)
+		}
+	}
+
+	var changes []recordChange
+	if len(creates) > 0 {
+		created, err := planCreate(creates, auth)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, created...)
+	}
+	if len(updates) > 0 {
+		updated, err := planUpdate(updates, auth, rollover)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, updated...)
+	}
+	return changes, nil
+}
+
+// runInventory creates or updates the records of every row with one listing
+// of zones and records. A failed row does not stop the others: each new host
+// is created on its own, and all updates share one run, so with rollover
+// there is a single propagation wait. It returns one result per row and an
+// error if any row failed.
+func runInventory(rows []inventoryRow, rollover bool, concurrency int) ([]inventoryResult, []recordResult, error) {
+	snapshot, err := newProviderSnapshot(cloudflareCredentials())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary := make([]inventoryResult, len(rows))
+	var results []recordResult
+	var updates []tlsaOptions
+	var updateRows []int
+	rowOf := make(map[string]int)
+	for i, row := range rows {
+		records, err := row.opts.records("Updated")
+		summary[i] = inventoryResult{Line: row.line, Host: row.opts.host(), Records: len(records)}
+		action := ""
+		if err == nil {
+			action, err = inventoryAction(snapshot, row.opts, rollover)
+		}
+		if err != nil {
+			summary[i].Action = "skip"
+			summary[i].Error = Redact(err.Error())
+			slog.Error("Skipping inventory row", "line", row.line, "host", row.opts.host(), "error", err)
+			continue
+		}
+		summary[i].Action = action
+
+		if action == actionCreate {
+			created, err := createRecords(snapshot, []tlsaOptions{row.opts})
+			results = append(results, created...)
+			if err != nil {
+				summary[i].Error = Redact(err.Error())
+			}
+			continue
+		}
+		updates = append(updates, row.opts)
+		updateRows = append(updateRows, i)
+		for _, record := range records {
+			rowOf[strings.ToLower(record.Name+"."+row.opts.URL)] = i
+		}
+	}
+
+	if len(updates) > 0 {
+		updated, err := updateRecords(context.Background(), snapshot, updates, rollover, false, concurrency)
+		results = append(results, updated...)
+		reported := make(map[int]bool)
+		for _, result := range updated {
+			i, ok := rowOf[strings.ToLower(result.Name)]
+			if !ok {
+				continue
+			}
+			reported[i] = true
+			if result.Error != "" && summary[i].Error == "" {
+				summary[i].Error = result.Error
+			}
+		}
+		// A run that failed before any record was attempted has no results
+		// for its rows, and none of them were published
+		if err != nil {
+			for _, i := range updateRows {
+				if !reported[i] && summary[i].Error == "" {
+					summary[i].Error = Redact(err.Error())
+				}
+			}
+		}
+	}
+
+	failed := 0
+	for _, row := range summary {
+		if row.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return summary, results, fmt.Errorf("%d of %d inventory rows failed", failed, len(rows))
+	}
+	return summary, results, nil
+}
+
+// writeInventoryResults writes the summary table, or with json the result
+// document, and returns runErr.
+func writeInventoryResults(w io.Writer, output string, summary []inventoryResult, results []recordResult, runErr error) error {
+	if output == "json" {
+		doc := inventoryDocument{
+			resultDocument: resultDocument{Command: "batch", Success: runErr == nil, Results: results},
+			Rows:           summary,
+		}
+		if doc.Results == nil {
+			doc.Results = []recordResult{}
+		}
+		if doc.Rows == nil {
+			doc.Rows = []inventoryResult{}
+		}
+		if runErr != nil {
+			doc.Error = Redact(runErr.Error())
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(doc); err != nil {
+			return fmt.Errorf("error writing results: %v", err)
+		}
+		return runErr
+	}
+	if summary == nil {
+		return runErr
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tHOST\tACTION\tRECORDS\tRESULT")
+	ok := 0
+	for _, row := range summary {
+		result := "ok"
+		if row.Error != "" {
+			result = "FAILED: " + row.Error
+		} else {
+			ok++
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n", row.Line, row.Host, row.Action, row.Records, result)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\n%d rows: %d ok, %d failed\n", len(summary), ok, len(summary)-ok)
+	return runErr
+}
@@ -0,0 +1,178 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseInventory(t *testing.T) {
+	content := `zone,subdomain,services,cert,usages
+# mail cluster
+example.com,mail,25 465/tcp;587,/etc/ssl/mail.pem,dane-ee dane-ta
+example.org, xmpp ,5269/tcp,/etc/ssl/xmpp.pem,
+`
+	rows, err := parseInventory(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("parseInventory() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+
+	mail, xmpp := rows[0], rows[1]
+	if mail.line != 3 || mail.opts.host() != "mail.example.com" || len(mail.opts.Services) != 3 || !mail.opts.DaneEE || !mail.opts.DaneTA {
+		t.Errorf("Unexpected mail row: %+v", mail)
+	}
+	if mail.opts.Services[1] != (tlsaService{Port: "465", Protocol: "tcp"}) || mail.opts.Cert != "/etc/ssl/mail.pem" {
+		t.Errorf("Unexpected mail services: %+v", mail.opts.Services)
+	}
+	// Usages default to DANE-EE
+	if xmpp.line != 4 || xmpp.opts.host() != "xmpp.example.org" || !xmpp.opts.DaneEE || xmpp.opts.DaneTA {
+		t.Errorf("Unexpected xmpp row: %+v", xmpp)
+	}
+}
+
+func TestParseInventory_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"empty", "", []string{"missing header line"}},
+		{"unknown column", "zone,host,services,cert\n", []string{`unknown column "host"`}},
+		{"missing column", "zone,subdomain,services\n", []string{`missing column "cert"`}},
+		{"no rows", "zone,subdomain,services,cert\n", []string{"at least one row is required"}},
+		{
+			"invalid rows",
+			"zone,subdomain,services,usages,cert\nexample.com,mail,smtp,,/c.pem\nexample.com,,25,pkix-ee,/c.pem\n",
+			[]string{`line 2: services: "smtp" is not a port`, "line 3: hostname is required", "line 3: usages[0]"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseInventory(strings.NewReader(tc.content))
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRunInventory(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com", "example.org")
+	cert := generateTestCertForReq(t)
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "0000", managedComment)
+	f.addRecord("zone-1", "_587._tcp.mail.example.com", 3, 1, 1, "0000", managedComment)
+	f.addRecord("zone-2", "_25._tcp.partial.example.org", 3, 1, 1, "0000", managedComment)
+
+	rows, err := parseInventory(strings.NewReader(fmt.Sprintf(`zone,subdomain,services,cert
+example.com,mail,25 587,%[1]s
+example.org,xmpp,5269,%[1]s
+example.org,partial,25 587,%[1]s
+example.net,mail,25,%[1]s
+`, cert)))
+	if err != nil {
+		t.Fatalf("parseInventory() error = %v", err)
+	}
+
+	summary, results, err := runInventory(rows, false, 2)
+	if err == nil || err.Error() != "2 of 4 inventory rows failed" {
+		t.Errorf("Expected 2 failed rows, got %v", err)
+	}
+
+	want := []struct {
+		action string
+		failed bool
+	}{{actionUpdate, false}, {actionCreate, false}, {"skip", true}, {"skip", true}}
+	for i, w := range want {
+		if summary[i].Action != w.action || (summary[i].Error != "") != w.failed {
+			t.Errorf("Row %d: expected %s (failed %v), got %+v", i, w.action, w.failed, summary[i])
+		}
+	}
+	if !strings.Contains(summary[2].Error, "1 of 2 TLSA records exist") {
+		t.Errorf("Unexpected error for partial row: %s", summary[2].Error)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 record results, got %+v", results)
+	}
+
+	// One listing for all rows
+	if n := f.countCalls("GET /zones") - f.countCalls("GET /zones/"); n != 1 {
+		t.Errorf("Expected 1 zone listing, got %d", n)
+	}
+	for _, record := range f.zoneRecords("zone-1") {
+		if record.Data.Certificate == "0000" {
+			t.Errorf("Expected %s to be updated", record.Name)
+		}
+	}
+	if n := len(f.zoneRecords("zone-2")); n != 2 {
+		t.Errorf("Expected the xmpp record to be created next to the partial host, got %d records", n)
+	}
+}
+
+func TestRunInventory_FailedUpdateRunFailsRows(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	mailCert, xmppCert := generateTestCertForReq(t), generateTestCertForReq(t)
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "0000", managedComment)
+
+	rows, err := parseInventory(strings.NewReader(fmt.Sprintf(`zone,subdomain,services,cert
+example.com,mail,25,%s
+example.com,xmpp,5269,%s
+`, mailCert, xmppCert)))
+	if err != nil {
+		t.Fatalf("parseInventory() error = %v", err)
+	}
+
+	// The certificate of the update row disappears after its row was
+	// checked, while the create row is written
+	f.onWrite = func() { os.Remove(mailCert) }
+
+	summary, _, err := runInventory(rows, false, 1)
+	if err == nil || err.Error() != "1 of 2 inventory rows failed" {
+		t.Errorf("Expected 1 failed row, got %v", err)
+	}
+	if summary[0].Action != actionUpdate || summary[0].Error == "" {
+		t.Errorf("Expected the update row to fail, got %+v", summary[0])
+	}
+	if summary[1].Error != "" {
+		t.Errorf("Expected the create row to succeed, got %+v", summary[1])
+	}
+}
+
+func TestWriteInventoryResults(t *testing.T) {
+	summary := []inventoryResult{
+		{Line: 2, Host: "mail.example.com", Action: actionUpdate, Records: 2},
+		{Line: 3, Host: "mail.example.net", Action: "skip", Records: 1, Error: "zone not found"},
+	}
+	runErr := fmt.Errorf("1 of 2 inventory rows failed")
+
+	var buf bytes.Buffer
+	if err := writeInventoryResults(&buf, "text", summary, nil, runErr); err != runErr {
+		t.Errorf("Expected run error to be returned, got %v", err)
+	}
+	for _, want := range []string{"LINE  HOST", "mail.example.com  update  2        ok", "FAILED: zone not found", "2 rows: 1 ok, 1 failed"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in output:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	writeInventoryResults(&buf, "json", summary, nil, runErr)
+	var doc inventoryDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc.Command != "batch" || doc.Success || len(doc.Rows) != 2 || doc.Results == nil {
+		t.Errorf("Unexpected document: %+v", doc)
+	}
+}
@@ -0,0 +1,198 @@
+package resource
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// actionRollback marks a record restored after a failed run.
+const actionRollback = "rollback"
+
+// journalEntry is one record write. Before is the record as it was, nil for a
+// create. After is the record as written, nil for a delete.
+type journalEntry struct {
+	ZoneID string
+	Action string
+	Before *DNSRecord
+	After  *DNSRecord
+}
+
+// journal records every write made through a providerSnapshot during a run,
+// so the run can be undone. It is safe for concurrent use.
+type journal struct {
+	mu      sync.Mutex
+	entries []journalEntry
+}
+
+func (j *journal) record(zoneID, action string, before, after *DNSRecord) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, journalEntry{ZoneID: zoneID, Action: action, Before: before, After: after})
+}
+
+// recordBatch journals the changes of a batch that took effect. before holds
+// the records of the zone as they were before the batch.
+func (j *journal) recordBatch(zoneID string, before []DNSRecord, batch recordBatch, result *batchResult) {
+	prior := func(id string) *DNSRecord {
+		for _, record := range before {
+			if record.ID == id {
+				return &record
+			}
+		}
+		return nil
+	}
+	for i := range result.Deletes {
+		j.record(zoneID, actionDelete, prior(batch.Deletes[i].ID), nil)
+	}
+	for i, updated := range result.Puts {
+		j.record(zoneID, actionUpdate, prior(batch.Puts[i].ID), &updated)
+	}
+	for _, created := range result.Posts {
+		j.record(zoneID, actionCreate, nil, &created)
+	}
+}
+
+// undo is the net change of a run to one record, and how to reverse it.
+type undo struct {
+	zoneID  string
+	id      string
+	name    string
+	created bool
+	before  *DNSRecord // the record before the run, nil if not known
+	deleted bool
+}
+
+// undos folds the journal into one undo per record, in the order the records
+// were first written. A record updated several times is restored to its
+// contents before the first update, and a record created and deleted again
+// needs nothing.
+func (j *journal) undos() []*undo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var order []*undo
+	byID := make(map[string]*undo)
+	for _, entry := range j.entries {
+		var u *undo
+		if entry.Before != nil {
+			u = byID[entry.Before.ID]
+		}
+		if u == nil {
+			u = &undo{zoneID: entry.ZoneID, created: entry.Action == actionCreate, before: entry.Before}
+			order = append(order, u)
+		}
+		// Follow the record to its current ID, which a provider may change
+		// on update
+		current := entry.Before
+		if entry.After != nil {
+			current = entry.After
+		}
+		if current != nil {
+			delete(byID, u.id)
+			u.id, u.name = current.ID, current.Name
+			byID[u.id] = u
+		}
+		u.deleted = entry.Action == actionDelete
+	}
+
+	var pending []*undo
+	for _, u := range order {
+		if u.created && u.deleted {
+			continue
+		}
+		pending = append(pending, u)
+	}
+	return pending
+}
+
+// recordRequest returns the request that writes the record as it is.
+func recordRequest(record DNSRecord) JSONRequest {
+	return JSONRequest{
+		Type: record.Type,
+		Name: record.Name,
+		Data: Data{
+			Usage:        record.Data.Usage,
+			Selector:     record.Data.Selector,
+			Matchingtype: record.Data.MatchingType,
+			Certificate:  record.Data.Certificate,
+		},
+		Ttl:     record.TTL,
+		Proxied: record.Proxied,
+		Comment: record.Comment,
+		Tags:    record.Tags,
+	}
+}
+
+// rollback reverses every write in the snapshot's journal with one batch per
+// zone: records the run created are deleted, updated records get their
+// previous contents back and deleted records are created again. It returns a
+// result for each record it restored or failed to restore. The rollback
+// itself is not journaled.
+func rollback(s *providerSnapshot) ([]recordResult, error) {
+	j := s.journal
+	s.journal = nil
+	if j == nil {
+		return nil, nil
+	}
+
+	var zones []string
+	byZone := make(map[string][]*undo)
+	for _, u := range j.undos() {
+		if _, ok := byZone[u.zoneID]; !ok {
+			zones = append(zones, u.zoneID)
+		}
+		byZone[u.zoneID] = append(byZone[u.zoneID], u)
+	}
+
+	var results []recordResult
+	var firstErr error
+	for _, zoneID := range zones {
+		var batch recordBatch
+		var deletes, puts, posts []*undo
+		for _, u := range byZone[zoneID] {
+			switch {
+			case u.created:
+				batch.Deletes = append(batch.Deletes, batchDelete{ID: u.id})
+				deletes = append(deletes, u)
+			case u.before == nil:
+				err := fmt.Errorf("previous contents of record %s are unknown", u.id)
+				slog.Error("Rollback failed, record left as changed by this run", "name", u.name, "id", u.id, "error", err)
+				results = append(results, newRecordResult(u.name, actionRollback, u.id, err))
+				if firstErr == nil {
+					firstErr = err
+				}
+			case u.deleted:
+				batch.Posts = append(batch.Posts, recordRequest(*u.before))
+				posts = append(posts, u)
+			default:
+				batch.Puts = append(batch.Puts, batchPut{ID: u.id, JSONRequest: recordRequest(*u.before)})
+				puts = append(puts, u)
+			}
+		}
+
+		result, err := s.batch(zoneID, batch)
+		if err != nil {
+			err = fmt.Errorf("error rolling back records in zone %s: %v", zoneID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		report := func(undos []*undo, done int, message string, ids func(k int) string) {
+			for k, u := range undos {
+				if k >= done {
+					slog.Error("Rollback failed, record left as changed by this run", "name", u.name, "id", u.id, "error", err)
+					results = append(results, newRecordResult(u.name, actionRollback, u.id, err))
+					continue
+				}
+				slog.Info(message, "name", u.name, "id", ids(k))
+				notify(eventRolledBack, u.name, ids(k), message, nil)
+				results = append(results, newRecordResult(u.name, actionRollback, ids(k), nil))
+			}
+		}
+		report(deletes, len(result.Deletes), "Rolled back: deleted TLSA record created by this run", func(k int) string { return deletes[k].id })
+		report(puts, len(result.Puts), "Rolled back: restored previous TLSA record contents", func(k int) string { return puts[k].id })
+		report(posts, len(result.Posts), "Rolled back: recreated deleted TLSA record", func(k int) string { return result.Posts[k].ID })
+	}
+	return results, firstErr
+}
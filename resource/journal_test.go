@@ -0,0 +1,126 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournal_Undos(t *testing.T) {
+	record := func(id, cert string) *DNSRecord {
+		var r DNSRecord
+		r.ID = id
+		r.Name = "_25._tcp.mail.example.com"
+		r.Data.Certificate = cert
+		return &r
+	}
+
+	j := &journal{}
+	j.record("zone-1", actionUpdate, record("a", "0000"), record("a", "1111"))
+	j.record("zone-1", actionUpdate, record("a", "1111"), record("a", "2222"))
+	j.record("zone-1", actionCreate, nil, record("b", "3333"))
+	j.record("zone-1", actionDelete, record("b", "3333"), nil)
+	j.record("zone-1", actionCreate, nil, record("c", "4444"))
+	j.record("zone-1", actionDelete, record("d", "5555"), nil)
+
+	undos := j.undos()
+	if len(undos) != 3 {
+		t.Fatalf("Expected 3 undos, got %d", len(undos))
+	}
+	if undos[0].id != "a" || undos[0].before.Data.Certificate != "0000" || undos[0].deleted {
+		t.Errorf("Expected a to be restored to its first contents, got %+v", undos[0])
+	}
+	if undos[1].id != "c" || !undos[1].created {
+		t.Errorf("Expected c to be deleted, got %+v", undos[1])
+	}
+	if undos[2].id != "d" || !undos[2].deleted || undos[2].before == nil {
+		t.Errorf("Expected d to be recreated, got %+v", undos[2])
+	}
+}
+
+func TestRunUpdateAll_RollbackOnError(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com", "example.net")
+	opts := syncTestOptions(t)
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, "0000", managedComment)
+	}
+	// The second host has no record to update, so its update fails
+	other := syncTestOptions(t)
+	other.URL = "example.net"
+
+	results, err := runUpdateAll(context.Background(), []tlsaOptions{opts, other}, false, true, 2)
+	if err == nil || !strings.Contains(err.Error(), "rolled back 2 records") {
+		t.Fatalf("Expected rolled back error, got %v", err)
+	}
+
+	for _, record := range f.zoneRecords("zone-1") {
+		if record.Data.Certificate != "0000" {
+			t.Errorf("Expected %s to be restored, got %s", record.Name, record.Data.Certificate)
+		}
+	}
+	rolledBack := 0
+	for _, result := range results {
+		if result.Action == actionRollback && result.Error == "" {
+			rolledBack++
+		}
+	}
+	if rolledBack != 2 {
+		t.Errorf("Expected 2 rollback results, got %+v", results)
+	}
+}
+
+func TestRunUpdateAll_RollbackAfterRollover(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, "0000", managedComment)
+	}
+
+	originalSleep, originalCheck := rolloverSleep, propagationCheck
+	rolloverSleep = func(context.Context, time.Duration) error { return nil }
+	propagationCheck = func(name string) error {
+		if strings.HasPrefix(name, "_587.") {
+			return errors.New("not visible on 8.8.8.8:53")
+		}
+		return nil
+	}
+	t.Cleanup(func() { rolloverSleep, propagationCheck = originalSleep, originalCheck })
+
+	_, err := runUpdateAll(context.Background(), []tlsaOptions{opts}, true, true, 4)
+	if !errors.Is(err, errPropagation) {
+		t.Fatalf("Expected propagation error, got %v", err)
+	}
+
+	// Both new records are deleted and the deleted old record of port 25 is
+	// created again
+	records := f.zoneRecords("zone-1")
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records after rollback, got %+v", records)
+	}
+	for _, record := range records {
+		if record.Data.Certificate != "0000" || record.Comment != managedComment {
+			t.Errorf("Expected the old record of %s, got %+v", record.Name, record)
+		}
+	}
+}
+
+func TestRunUpdateAll_NoRollbackByDefault(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com", "example.net")
+	opts := syncTestOptions(t)
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, "0000", managedComment)
+	}
+	other := syncTestOptions(t)
+	other.URL = "example.net"
+
+	if _, err := runUpdateAll(context.Background(), []tlsaOptions{opts, other}, false, false, 2); err == nil {
+		t.Fatal("Expected error for missing record")
+	}
+	for _, record := range f.zoneRecords("zone-1") {
+		if record.Data.Certificate == "0000" {
+			t.Errorf("Expected %s to stay updated without rollback", record.Name)
+		}
+	}
+}
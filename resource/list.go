@@ -0,0 +1,134 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// listedRecord is the output form of a TLSA record for list.
+type listedRecord struct {
+	Name         string    `json:"name" yaml:"name"`
+	Usage        int       `json:"usage" yaml:"usage"`
+	Selector     int       `json:"selector" yaml:"selector"`
+	MatchingType int       `json:"matching_type" yaml:"matching_type"`
+	Certificate  string    `json:"certificate" yaml:"certificate"`
+	TTL          int       `json:"ttl" yaml:"ttl"`
+	Comment      string    `json:"comment" yaml:"comment"`
+	Managed      bool      `json:"managed" yaml:"managed"`
+	ModifiedOn   time.Time `json:"modified_on" yaml:"modified_on"`
+}
+
+func ResourceList(cmd *cobra.Command, args []string) error {
+	url, err := cmd.Flags().GetString("url")
+	if err != nil {
+		return err
+	}
+
+	subdomain, err := cmd.Flags().GetString("subdomain")
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+
+	noTrunc, err := cmd.Flags().GetBool("no-trunc")
+	if err != nil {
+		return err
+	}
+
+	records, err := listRecords(cloudflareCredentials(), url, subdomain)
+	if err != nil {
+		return err
+	}
+
+	return writeRecords(os.Stdout, records, output, noTrunc)
+}
+
+// listRecords returns the TLSA records in the zone of url, limited to the
+// records of one host when subdomain is set.
+func listRecords(auth cloudflareAuth, url, subdomain string) ([]listedRecord, error) {
+	zones, err := listZones(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, ok := findZone(zones, url)
+	if !ok {
+		return nil, fmt.Errorf("no matching zone found for %s", url)
+	}
+
+	records, err := listTLSARecords(zone.ID, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	host := strings.ToLower(url)
+	if subdomain != "" {
+		host = hostName(subdomain, url)
+	}
+
+	var listed []listedRecord
+	for _, record := range records {
+		name := strings.ToLower(record.Name)
+		if subdomain != "" && tlsaHost(name) != host {
+			continue
+		}
+		if subdomain == "" && name != host && !strings.HasSuffix(name, "."+host) {
+			continue
+		}
+		listed = append(listed, listedRecord{
+			Name:         record.Name,
+			Usage:        record.Data.Usage,
+			Selector:     record.Data.Selector,
+			MatchingType: record.Data.MatchingType,
+			Certificate:  record.Data.Certificate,
+			TTL:          record.TTL,
+			Comment:      record.Comment,
+			Managed:      isManaged(record),
+			ModifiedOn:   record.ModifiedOn,
+		})
+	}
+	return listed, nil
+}
+
+func writeRecords(w io.Writer, records []listedRecord, output string, noTrunc bool) error {
+	switch output {
+	case "json":
+		if records == nil {
+			records = []listedRecord{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "yaml":
+		if records == nil {
+			records = []listedRecord{}
+		}
+		return yaml.NewEncoder(w).Encode(records)
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tUSAGE\tSELECTOR\tMATCHING TYPE\tCERTIFICATE\tTTL\tCOMMENT\tMODIFIED")
+		for _, record := range records {
+			certificate := record.Certificate
+			if !noTrunc && len(certificate) > 16 {
+				certificate = certificate[:16] + "..."
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%d\t%s\t%s\n",
+				record.Name, record.Usage, record.Selector, record.MatchingType, certificate,
+				record.TTL, record.Comment, record.ModifiedOn.Format("2006-01-02 15:04:05"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q, must be one of table, json, yaml", output)
+}
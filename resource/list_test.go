@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestListRecords(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", "Created by GoTLSAFlare - 2024-01-01 00:00:00")
+	f.addRecord("zone-1", "_443._tcp.www.example.com", 3, 1, 1, "bbbb", "")
+	f.addRecord("zone-1", "_25._tcp.mx.mail.example.com", 3, 1, 1, "cccc", "")
+	f.addRecord("zone-1", "_25._tcp.example.com", 3, 1, 1, "dddd", "")
+
+	all, err := listRecords(cloudflareCredentials(), "example.com", "")
+	if err != nil {
+		t.Fatalf("listRecords() error = %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected 4 records in zone, got %d", len(all))
+	}
+
+	mail, err := listRecords(cloudflareCredentials(), "example.com", "mail")
+	if err != nil {
+		t.Fatalf("listRecords() error = %v", err)
+	}
+	if len(mail) != 1 || mail[0].Certificate != "aaaa" || !mail[0].Managed {
+		t.Errorf("Expected only the managed mail record, got %+v", mail)
+	}
+
+	apex, err := listRecords(cloudflareCredentials(), "example.com", "@")
+	if err != nil {
+		t.Fatalf("listRecords() error = %v", err)
+	}
+	if len(apex) != 1 || apex[0].Certificate != "dddd" {
+		t.Errorf("Expected only the apex record, got %+v", apex)
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	records := []listedRecord{{
+		Name:         "_25._tcp.mail.example.com",
+		Usage:        3,
+		Selector:     1,
+		MatchingType: 1,
+		Certificate:  strings.Repeat("ab", 32),
+		TTL:          3600,
+		Comment:      "Created by GoTLSAFlare",
+	}}
+
+	var buf bytes.Buffer
+	if err := writeRecords(&buf, records, "table", false); err != nil {
+		t.Fatalf("writeRecords(table) error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "MATCHING TYPE") || !strings.Contains(buf.String(), "abababababababab...") {
+		t.Errorf("Unexpected table output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeRecords(&buf, records, "json", false); err != nil {
+		t.Fatalf("writeRecords(json) error = %v", err)
+	}
+	var fromJSON []listedRecord
+	if err := json.Unmarshal(buf.Bytes(), &fromJSON); err != nil || fromJSON[0].Certificate != records[0].Certificate {
+		t.Errorf("Unexpected JSON output (%v):\n%s", err, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeRecords(&buf, records, "yaml", false); err != nil {
+		t.Fatalf("writeRecords(yaml) error = %v", err)
+	}
+	var fromYAML []listedRecord
+	if err := yaml.Unmarshal(buf.Bytes(), &fromYAML); err != nil || fromYAML[0].MatchingType != 1 {
+		t.Errorf("Unexpected YAML output (%v):\n%s", err, buf.String())
+	}
+
+	if err := writeRecords(&buf, records, "xml", false); err == nil {
+		t.Error("Expected error for unknown output format")
+	}
+}
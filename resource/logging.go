@@ -0,0 +1,40 @@
+package resource
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// ConfigureLogging installs the default logger from --log-format and
+// --log-level. Logs always go to stderr so stdout stays parseable.
+func ConfigureLogging(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("log-format")
+	level, _ := cmd.Flags().GetString("log-level")
+
+	handler, err := newLogHandler(os.Stderr, format, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func newLogHandler(w io.Writer, format, level string) (slog.Handler, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q, must be debug, info, warn or error", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "text":
+		return redactHandler{next: slog.NewTextHandler(w, opts)}, nil
+	case "json":
+		return redactHandler{next: slog.NewJSONHandler(w, opts)}, nil
+	}
+	return nil, fmt.Errorf("invalid log format %q, must be text or json", format)
+}
@@ -0,0 +1,54 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewLogHandler_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := newLogHandler(&buf, "json", "warn")
+	if err != nil {
+		t.Fatalf("newLogHandler() error = %v", err)
+	}
+	logger := slog.New(handler)
+
+	logger.Info("Created TLSA record", "name", "_25._tcp.mail.example.com")
+	if buf.Len() != 0 {
+		t.Errorf("Expected info to be filtered at warn level, got %s", buf.String())
+	}
+
+	logger.Warn("Filesystem watch error", "error", "boom")
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "Filesystem watch error" || entry["error"] != "boom" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+}
+
+func TestNewLogHandler_Text(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := newLogHandler(&buf, "text", "debug")
+	if err != nil {
+		t.Fatalf("newLogHandler() error = %v", err)
+	}
+
+	slog.New(handler).Debug("Queried nameserver", "nameserver", "1.1.1.1:53")
+	if !strings.Contains(buf.String(), "level=DEBUG") || !strings.Contains(buf.String(), "nameserver=1.1.1.1:53") {
+		t.Errorf("Unexpected text log line: %s", buf.String())
+	}
+}
+
+func TestNewLogHandler_Invalid(t *testing.T) {
+	if _, err := newLogHandler(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("Expected error for invalid log format")
+	}
+	if _, err := newLogHandler(&bytes.Buffer{}, "json", "verbose"); err == nil {
+		t.Error("Expected error for invalid log level")
+	}
+}
@@ -0,0 +1,148 @@
+package resource
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gotlsaflare",
+		Name:      "api_requests_total",
+		Help:      "Cloudflare API requests by method and HTTP status code (\"error\" for transport failures).",
+	}, []string{"method", "status"})
+
+	recordChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gotlsaflare",
+		Name:      "records_changed_total",
+		Help:      "TLSA records created, updated or deleted.",
+	}, []string{"action"})
+
+	rolloverPhaseSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gotlsaflare",
+		Name:      "rollover_phase_duration_seconds",
+		Help:      "Duration of each rollover phase.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 4, 10),
+	}, []string{"phase"})
+
+	lastSyncTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gotlsaflare",
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last successful publish of a TLSA record name.",
+	}, []string{"name"})
+
+	certExpiryDays = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gotlsaflare",
+		Name:      "certificate_expiry_days",
+		Help:      "Days until the end-entity certificate of a managed certificate file expires.",
+	}, []string{"cert"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		apiRequests,
+		recordChanges,
+		rolloverPhaseSeconds,
+		lastSyncTimestamp,
+		certExpiryDays,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// cloudflareClient returns the HTTP client used for every Cloudflare API call.
+func cloudflareClient() *http.Client {
+	return &http.Client{Transport: metricsTransport{next: http.DefaultTransport}}
+}
+
+// metricsTransport counts API requests, and record changes for successful
+// writes to dns_records.
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		apiRequests.WithLabelValues(req.Method, "error").Inc()
+		return resp, err
+	}
+	apiRequests.WithLabelValues(req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+
+	// Batch requests are counted per record by applyBatch
+	if resp.StatusCode < 400 && strings.Contains(req.URL.Path, "/dns_records") && !strings.HasSuffix(req.URL.Path, "/batch") {
+		switch req.Method {
+		case "POST":
+			recordChanges.WithLabelValues("created").Inc()
+		case "PUT", "PATCH":
+			recordChanges.WithLabelValues("updated").Inc()
+		case "DELETE":
+			recordChanges.WithLabelValues("deleted").Inc()
+		}
+	}
+	return resp, nil
+}
+
+// markSynced records a successful publish of a TLSA record name.
+func markSynced(name string) {
+	lastSyncTimestamp.WithLabelValues(strings.ToLower(name)).SetToCurrentTime()
+}
+
+// observePhase records how long a rollover phase took since start.
+func observePhase(phase string, start time.Time) {
+	rolloverPhaseSeconds.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+}
+
+// updateCertExpiry sets the days-to-expiry gauge for a certificate file.
+func updateCertExpiry(certfile string) error {
+	notAfter, err := certNotAfter(certfile)
+	if err != nil {
+		return err
+	}
+	certExpiryDays.WithLabelValues(certfile).Set(time.Until(notAfter).Hours() / 24)
+	return nil
+}
+
+func certNotAfter(certfile string) (time.Time, error) {
+	cert, err := leafCertificate(certfile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// serveMetrics exposes /metrics on addr in the background and returns the
+// address it listens on. An empty addr disables the endpoint.
+func serveMetrics(addr string) (net.Addr, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error starting metrics listener: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			slog.Error("Metrics server stopped", "error", err)
+		}
+	}()
+
+	slog.Info("Serving metrics", "url", "http://"+listener.Addr().String()+"/metrics")
+	return listener.Addr(), nil
+}
@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsTransport_CountsRequestsAndChanges(t *testing.T) {
+	newFakeCloudflare(t, "example.com")
+	auth := cloudflareCredentials()
+
+	getsBefore := testutil.ToFloat64(apiRequests.WithLabelValues("GET", "200"))
+	createdBefore := testutil.ToFloat64(recordChanges.WithLabelValues("created"))
+	deletedBefore := testutil.ToFloat64(recordChanges.WithLabelValues("deleted"))
+
+	if _, err := listZones(auth); err != nil {
+		t.Fatalf("listZones() error = %v", err)
+	}
+	record, err := createRecord("zone-1", auth, JSONRequest{Type: "TLSA", Name: "_25._tcp.mail", Data: Data{Usage: 3, Selector: 1, Matchingtype: 1, Certificate: "aa"}})
+	if err != nil {
+		t.Fatalf("createRecord() error = %v", err)
+	}
+	if err := deleteRecord("zone-1", record.ID, auth); err != nil {
+		t.Fatalf("deleteRecord() error = %v", err)
+	}
+	// A failed delete must not count as a change
+	deleteRecord("zone-1", record.ID, auth)
+
+	if got := testutil.ToFloat64(apiRequests.WithLabelValues("GET", "200")) - getsBefore; got != 1 {
+		t.Errorf("Expected 1 GET 200, got %v", got)
+	}
+	if got := testutil.ToFloat64(recordChanges.WithLabelValues("created")) - createdBefore; got != 1 {
+		t.Errorf("Expected 1 created record, got %v", got)
+	}
+	if got := testutil.ToFloat64(recordChanges.WithLabelValues("deleted")) - deletedBefore; got != 1 {
+		t.Errorf("Expected 1 deleted record, got %v", got)
+	}
+}
+
+func TestUpdateCertExpiry(t *testing.T) {
+	certPath := generateTestCertForReq(t)
+
+	if err := updateCertExpiry(certPath); err != nil {
+		t.Fatalf("updateCertExpiry() error = %v", err)
+	}
+
+	days := testutil.ToFloat64(certExpiryDays.WithLabelValues(certPath))
+	if days < 364 || days > 366 {
+		t.Errorf("Expected about 365 days to expiry, got %v", days)
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	if addr, err := serveMetrics(""); addr != nil || err != nil {
+		t.Errorf("Expected empty address to disable metrics, got %v, %v", addr, err)
+	}
+
+	markSynced("_25._tcp.Mail.example.com")
+
+	addr, err := serveMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("serveMetrics() error = %v", err)
+	}
+
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to fetch metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, metric := range []string{
+		`gotlsaflare_last_successful_sync_timestamp_seconds{name="_25._tcp.mail.example.com"}`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), metric) {
+			t.Errorf("Expected metrics output to contain %s", metric)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// txtRequest is the body for writing a TXT record.
+type txtRequest struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	Ttl     int    `json:"ttl"`
+	Comment string `json:"comment"`
+}
+
+const (
+	mtaSTSVersion = "v=STSv1"
+	tlsRPTVersion = "v=TLSRPTv1"
+
+	// maxMTASTSAge is the longest max_age RFC 8461 allows, about a year.
+	maxMTASTSAge = 31557600
+)
+
+func ResourceMTASTS(cmd *cobra.Command, args []string) error {
+	domain, _ := cmd.Flags().GetString("url")
+	mode, _ := cmd.Flags().GetString("mode")
+	maxAge, _ := cmd.Flags().GetInt("max-age")
+	mx, _ := cmd.Flags().GetStringSlice("mx")
+	policyFile, _ := cmd.Flags().GetString("policy-file")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	ttl, err := txtTTL(cmd)
+	if err != nil {
+		return err
+	}
+
+	if len(mx) == 0 {
+		resolver, _ := cmd.Flags().GetString("resolver")
+		if resolver == "" {
+			resolver = systemResolver()
+		}
+		if mx, err = mxHosts(domain, resolver); err != nil {
+			return err
+		}
+	}
+
+	policy, err := mtaSTSPolicy(mode, mx, maxAge)
+	if err != nil {
+		return err
+	}
+
+	if policyFile == "" || dryRun {
+		fmt.Fprint(os.Stdout, policy)
+	} else {
+		if err := writeFileAtomic(policyFile, []byte(policy)); err != nil {
+			return err
+		}
+		slog.Info("Wrote MTA-STS policy, serve it at https://mta-sts."+domain+"/.well-known/mta-sts.txt", "path", policyFile)
+	}
+
+	content := fmt.Sprintf("%s; id=%s", mtaSTSVersion, mtaSTSPolicyID(policy))
+	return publishTXT(cloudflareCredentials(), "_mta-sts."+domain, mtaSTSVersion, content, ttl, dryRun)
+}
+
+func ResourceTLSRPT(cmd *cobra.Command, args []string) error {
+	domain, _ := cmd.Flags().GetString("url")
+	rua, _ := cmd.Flags().GetStringSlice("rua")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	ttl, err := txtTTL(cmd)
+	if err != nil {
+		return err
+	}
+
+	content, err := tlsRPTRecord(rua)
+	if err != nil {
+		return err
+	}
+	return publishTXT(cloudflareCredentials(), "_smtp._tls."+domain, tlsRPTVersion, content, ttl, dryRun)
+}
+
+func txtTTL(cmd *cobra.Command) (int, error) {
+	value, _ := cmd.Flags().GetString("ttl")
+	ttl, err := parseTTL(value)
+	if err != nil {
+		return 0, err
+	}
+	if ttl != ttlAuto && (ttl < 60 || ttl > 86400) {
+		return 0, fmt.Errorf("TTL %d must be auto (1) or between 60 and 86400 seconds", ttl)
+	}
+	return ttl, nil
+}
+
+// mxHosts returns the MX targets of domain, which the policy must list.
+func mxHosts(domain, resolver string) ([]string, error) {
+	targets, err := discoverTargets(domain, nil, resolver)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, target := range targets {
+		hosts = append(hosts, target.Host)
+	}
+	return hosts, nil
+}
+
+// mtaSTSPolicy returns the mta-sts.txt policy file of RFC 8461.
+func mtaSTSPolicy(mode string, mx []string, maxAge int) (string, error) {
+	switch mode {
+	case "enforce", "testing", "none":
+	default:
+		return "", fmt.Errorf("invalid MTA-STS mode %q, must be one of enforce, testing, none", mode)
+	}
+	if maxAge < 1 || maxAge > maxMTASTSAge {
+		return "", fmt.Errorf("max-age %d must be between 1 and %d seconds", maxAge, maxMTASTSAge)
+	}
+	if len(mx) == 0 && mode != "none" {
+		return "", fmt.Errorf("the policy must list at least one MX host")
+	}
+
+	var b strings.Builder
+	b.WriteString("version: STSv1\r\n")
+	fmt.Fprintf(&b, "mode: %s\r\n", mode)
+	for _, host := range mx {
+		fmt.Fprintf(&b, "mx: %s\r\n", targetHost(host))
+	}
+	fmt.Fprintf(&b, "max_age: %d\r\n", maxAge)
+	return b.String(), nil
+}
+
+// mtaSTSPolicyID derives the id of the _mta-sts record from the policy, so
+// the id changes exactly when the policy does and senders fetch it again.
+func mtaSTSPolicyID(policy string) string {
+	sum := sha256.Sum256([]byte(policy))
+	return hex.EncodeToString(sum[:10])
+}
+
+// tlsRPTRecord returns the _smtp._tls record of RFC 8460 reporting to rua.
+func tlsRPTRecord(rua []string) (string, error) {
+	if len(rua) == 0 {
+		return "", fmt.Errorf("at least one --rua address is required")
+	}
+	for _, uri := range rua {
+		if !strings.HasPrefix(uri, "mailto:") && !strings.HasPrefix(uri, "https://") {
+			return "", fmt.Errorf("invalid report URI %q, must start with mailto: or https://", uri)
+		}
+		if strings.ContainsAny(uri, ",; ") {
+			return "", fmt.Errorf("invalid report URI %q, must not contain commas, semicolons or spaces", uri)
+		}
+	}
+	return fmt.Sprintf("%s; rua=%s", tlsRPTVersion, strings.Join(rua, ",")), nil
+}
+
+// publishTXT makes content the only TXT record at name starting with
+// version. A managed record is updated in place; a record written by hand is
+// never taken over, since two policy records would disable the policy.
+func publishTXT(auth cloudflareAuth, name, version, content string, ttl int, dryRun bool) error {
+	name = strings.ToLower(name)
+	zones, err := listZones(auth)
+	if err != nil {
+		return err
+	}
+	zone, ok := findZone(zones, name)
+	if !ok {
+		return fmt.Errorf("no matching zone found for %s", name)
+	}
+	records, err := listRecordsOfType(zone.ID, "TXT", auth)
+	if err != nil {
+		return err
+	}
+
+	var existing []DNSRecord
+	for _, record := range records {
+		if strings.EqualFold(record.Name, name) && strings.HasPrefix(txtContent(record.Content), version) {
+			existing = append(existing, record)
+		}
+	}
+	if len(existing) > 1 {
+		return fmt.Errorf("%d %s records found at %s, remove all but one", len(existing), version, name)
+	}
+
+	req := txtRequest{Type: "TXT", Name: name, Content: content, Ttl: ttl}
+	if len(existing) == 0 {
+		req.Comment = recordComment("Created")
+		if dryRun {
+			fmt.Printf("Would create TXT %s %q\n", name, content)
+			return nil
+		}
+		created, err := writeRecord("POST", "/zones/"+zone.ID+"/dns_records", auth, req)
+		if err != nil {
+			return fmt.Errorf("error creating TXT record %s: %v", name, err)
+		}
+		slog.Info("Created TXT record", "name", name, "id", created.ID, "content", content)
+		return nil
+	}
+
+	old := existing[0]
+	if !isManaged(old) {
+		return fmt.Errorf("TXT record %s was not written by gotlsaflare, delete it or add %q to its comment to manage it", name, managedMarker)
+	}
+	if txtContent(old.Content) == content && old.TTL == ttl {
+		slog.Info("TXT record is up to date", "name", name, "content", content)
+		return nil
+	}
+	req.Comment = recordComment("Updated")
+	if dryRun {
+		fmt.Printf("Would update TXT %s %q -> %q\n", name, txtContent(old.Content), content)
+		return nil
+	}
+	if _, err := writeRecord("PUT", "/zones/"+zone.ID+"/dns_records/"+old.ID, auth, req); err != nil {
+		return fmt.Errorf("error updating TXT record %s: %v", name, err)
+	}
+	slog.Info("Updated TXT record", "name", name, "id", old.ID, "content", content)
+	return nil
+}
+
+// txtContent returns TXT record content without the quotes Cloudflare may
+// return it in.
+func txtContent(content string) string {
+	if len(content) >= 2 && strings.HasPrefix(content, `"`) && strings.HasSuffix(content, `"`) {
+		return strings.ReplaceAll(content[1:len(content)-1], `" "`, "")
+	}
+	return content
+}
@@ -0,0 +1,141 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestMTASTSPolicy(t *testing.T) {
+	policy, err := mtaSTSPolicy("enforce", []string{"MX1.example.com.", "*.mx.example.net"}, 604800)
+	if err != nil {
+		t.Fatalf("mtaSTSPolicy() error = %v", err)
+	}
+	want := "version: STSv1\r\nmode: enforce\r\nmx: mx1.example.com\r\nmx: *.mx.example.net\r\nmax_age: 604800\r\n"
+	if policy != want {
+		t.Errorf("Unexpected policy:\n%q\nwant\n%q", policy, want)
+	}
+
+	// The id follows the policy
+	testingPolicy, _ := mtaSTSPolicy("testing", []string{"mx1.example.com"}, 604800)
+	if mtaSTSPolicyID(policy) == mtaSTSPolicyID(testingPolicy) || mtaSTSPolicyID(policy) != mtaSTSPolicyID(want) || len(mtaSTSPolicyID(policy)) != 20 {
+		t.Errorf("Unexpected policy ids %s and %s", mtaSTSPolicyID(policy), mtaSTSPolicyID(testingPolicy))
+	}
+
+	for _, tc := range []struct {
+		mode   string
+		mx     []string
+		maxAge int
+	}{
+		{"strict", []string{"mx1.example.com"}, 604800},
+		{"enforce", nil, 604800},
+		{"enforce", []string{"mx1.example.com"}, maxMTASTSAge + 1},
+	} {
+		if _, err := mtaSTSPolicy(tc.mode, tc.mx, tc.maxAge); err == nil {
+			t.Errorf("Expected error for %+v", tc)
+		}
+	}
+}
+
+func TestTLSRPTRecord(t *testing.T) {
+	record, err := tlsRPTRecord([]string{"mailto:tlsrpt@example.com", "https://reports.example.com/tlsrpt"})
+	if err != nil || record != "v=TLSRPTv1; rua=mailto:tlsrpt@example.com,https://reports.example.com/tlsrpt" {
+		t.Errorf("tlsRPTRecord() = %q, %v", record, err)
+	}
+
+	for _, rua := range [][]string{nil, {"tlsrpt@example.com"}, {"mailto:a@example.com,b@example.com"}} {
+		if _, err := tlsRPTRecord(rua); err == nil {
+			t.Errorf("Expected error for %v", rua)
+		}
+	}
+}
+
+func TestPublishTXT(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	auth := cloudflareCredentials()
+	txtRecords := func() []DNSRecord {
+		var records []DNSRecord
+		for _, record := range f.zoneRecords("zone-1") {
+			if record.Type == "TXT" {
+				records = append(records, record)
+			}
+		}
+		return records
+	}
+
+	if err := publishTXT(auth, "_mta-sts.example.com", mtaSTSVersion, "v=STSv1; id=one", 3600, true); err != nil || len(txtRecords()) != 0 {
+		t.Fatalf("Expected dry run to change nothing, got %v", err)
+	}
+	if err := publishTXT(auth, "_mta-sts.example.com", mtaSTSVersion, "v=STSv1; id=one", 3600, false); err != nil {
+		t.Fatalf("publishTXT() error = %v", err)
+	}
+	if err := publishTXT(auth, "_mta-sts.example.com", mtaSTSVersion, "v=STSv1; id=one", 3600, false); err != nil {
+		t.Fatalf("publishTXT() error = %v", err)
+	}
+	if n := f.countCalls("PUT"); n != 0 {
+		t.Errorf("Expected an unchanged record not to be written, got %d updates", n)
+	}
+	if err := publishTXT(auth, "_mta-sts.example.com", mtaSTSVersion, "v=STSv1; id=two", 3600, false); err != nil {
+		t.Fatalf("publishTXT() error = %v", err)
+	}
+
+	records := txtRecords()
+	if len(records) != 1 || records[0].Content != "v=STSv1; id=two" || !isManaged(records[0]) {
+		t.Errorf("Expected one managed record with the new id, got %+v", records)
+	}
+
+	// A record written by hand is never replaced
+	f.mu.Lock()
+	f.records["zone-1"][0].Comment = "added by ops"
+	f.mu.Unlock()
+	if err := publishTXT(auth, "_mta-sts.example.com", mtaSTSVersion, "v=STSv1; id=three", 3600, false); err == nil || !strings.Contains(err.Error(), "not written by gotlsaflare") {
+		t.Errorf("Expected error for unmanaged record, got %v", err)
+	}
+}
+
+func TestResourceMTASTS(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	path := filepath.Join(t.TempDir(), "mta-sts.txt")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("url", "", "")
+	cmd.Flags().String("ttl", "3600", "")
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.Flags().String("mode", "testing", "")
+	cmd.Flags().Int("max-age", 604800, "")
+	cmd.Flags().StringSlice("mx", nil, "")
+	cmd.Flags().String("resolver", "", "")
+	cmd.Flags().String("policy-file", "", "")
+	resolver := newFakeResolver(t, "example.com. 300 IN MX 10 mx1.example.com.")
+	if err := cmd.ParseFlags([]string{"--url", "example.com", "--resolver", resolver, "--policy-file", path}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if err := ResourceMTASTS(cmd, nil); err != nil {
+		t.Fatalf("ResourceMTASTS() error = %v", err)
+	}
+
+	policy, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(policy), "mx: mx1.example.com\r\n") {
+		t.Errorf("Unexpected policy file: %q (%v)", policy, err)
+	}
+	records := f.zoneRecords("zone-1")
+	if len(records) != 1 || records[0].Name != "_mta-sts.example.com" || records[0].Content != "v=STSv1; id="+mtaSTSPolicyID(string(policy)) {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+}
+
+func TestTxtContent(t *testing.T) {
+	for content, want := range map[string]string{
+		`"v=STSv1; id=one"`:                         "v=STSv1; id=one",
+		`"v=TLSRPTv1; " "rua=mailto:a@example.com"`: "v=TLSRPTv1; rua=mailto:a@example.com",
+		"v=STSv1; id=one":                           "v=STSv1; id=one",
+	} {
+		if got := txtContent(content); got != want {
+			t.Errorf("txtContent(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Events sent to notifiers.
+const (
+	eventPublished          = "published"
+	eventFailed             = "failed"
+	eventRolloverFinalized  = "rollover_finalized"
+	eventPropagationFailed  = "propagation_failed"
+	eventOldRecordPreserved = "old_record_preserved"
+	eventRolledBack         = "rolled_back"
+)
+
+var notifyEvents = []string{eventPublished, eventFailed, eventRolloverFinalized, eventPropagationFailed, eventOldRecordPreserved, eventRolledBack}
+
+// errPropagation marks a rollover stopped by a failed propagation check, which
+// is already reported by its own events.
+var errPropagation = errors.New("DNS propagation check failed")
+
+// notifyEvent is the JSON document posted to webhooks and passed to scripts.
+type notifyEvent struct {
+	Event    string    `json:"event"`
+	Name     string    `json:"name"`
+	RecordID string    `json:"record_id,omitempty"`
+	Message  string    `json:"message"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+func (e notifyEvent) summary() string {
+	summary := fmt.Sprintf("[gotlsaflare] %s %s: %s", e.Event, e.Name, e.Message)
+	if e.Error != "" {
+		summary += " (" + e.Error + ")"
+	}
+	return summary
+}
+
+type notifier interface {
+	notify(ctx context.Context, event notifyEvent) error
+}
+
+// notifiers receive every event in notifyOn. They are set once per command
+// by configureNotifiers.
+var (
+	notifiers []notifier
+	notifyOn  []string
+)
+
+const notifyTimeout = 30 * time.Second
+
+// configureNotifiers sets up the notifiers from the --notify-* flags.
+func configureNotifiers(cmd *cobra.Command) error {
+	webhooks, _ := cmd.Flags().GetStringSlice("notify-webhook")
+	slackHooks, _ := cmd.Flags().GetStringSlice("notify-slack")
+	scripts, _ := cmd.Flags().GetStringSlice("notify-exec")
+	recipients, _ := cmd.Flags().GetStringSlice("notify-email")
+	events, _ := cmd.Flags().GetStringSlice("notify-on")
+
+	for _, event := range events {
+		if !slices.Contains(notifyEvents, event) {
+			return fmt.Errorf("invalid notify event %q, must be one of %s", event, strings.Join(notifyEvents, ", "))
+		}
+	}
+
+	var configured []notifier
+	for _, url := range webhooks {
+		configured = append(configured, webhookNotifier{url: url})
+	}
+	for _, url := range slackHooks {
+		configured = append(configured, slackNotifier{url: url})
+	}
+	for _, path := range scripts {
+		configured = append(configured, execNotifier{path: path})
+	}
+	if len(recipients) > 0 {
+		server, _ := cmd.Flags().GetString("smtp-server")
+		from, _ := cmd.Flags().GetString("smtp-from")
+		username, _ := cmd.Flags().GetString("smtp-username")
+		if server == "" || from == "" {
+			return fmt.Errorf("--notify-email requires --smtp-server and --smtp-from")
+		}
+		configured = append(configured, smtpNotifier{
+			addr:     server,
+			from:     from,
+			to:       recipients,
+			username: username,
+			password: os.Getenv("SMTP_PASSWORD"),
+		})
+	}
+
+	notifiers = configured
+	notifyOn = events
+	return nil
+}
+
+// notify sends the event to every notifier. Failures are logged and never
+// fail the run.
+func notify(event, name, recordID, message string, err error) {
+	if len(notifiers) == 0 || (len(notifyOn) > 0 && !slices.Contains(notifyOn, event)) {
+		return
+	}
+
+	e := notifyEvent{Event: event, Name: name, RecordID: recordID, Message: message, Time: time.Now().UTC()}
+	if err != nil {
+		e.Error = Redact(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	for _, n := range notifiers {
+		if err := n.notify(ctx, e); err != nil {
+			slog.Warn("Notification failed", "event", event, "name", name, "error", err)
+		}
+	}
+}
+
+// webhookNotifier posts the event as JSON.
+type webhookNotifier struct {
+	url string
+}
+
+func (n webhookNotifier) notify(ctx context.Context, event notifyEvent) error {
+	return postJSON(ctx, n.url, event)
+}
+
+// slackNotifier posts a one-line message to a Slack-compatible incoming
+// webhook.
+type slackNotifier struct {
+	url string
+}
+
+func (n slackNotifier) notify(ctx context.Context, event notifyEvent) error {
+	return postJSON(ctx, n.url, map[string]string{"text": event.summary()})
+}
+
+func postJSON(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding notification: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating notification request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("notification webhook returned status: %s", resp.Status)
+	}
+	return nil
+}
+
+// execNotifier runs a script with the event in GOTLSAFLARE_* environment
+// variables and as JSON on stdin.
+type execNotifier struct {
+	path string
+}
+
+func (n execNotifier) notify(ctx context.Context, event notifyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding notification: %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, n.path)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"GOTLSAFLARE_EVENT="+event.Event,
+		"GOTLSAFLARE_NAME="+event.Name,
+		"GOTLSAFLARE_RECORD_ID="+event.RecordID,
+		"GOTLSAFLARE_MESSAGE="+event.Message,
+		"GOTLSAFLARE_ERROR="+event.Error,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify script %s failed: %v: %s", n.path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// smtpNotifier sends the event as a plain text email. The password is read
+// from SMTP_PASSWORD.
+type smtpNotifier struct {
+	addr     string
+	from     string
+	to       []string
+	username string
+	password string
+}
+
+func (n smtpNotifier) notify(ctx context.Context, event notifyEvent) error {
+	var auth smtp.Auth
+	if n.username != "" {
+		host, _, err := net.SplitHostPort(n.addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP server %s: %v", n.addr, err)
+		}
+		auth = smtp.PlainAuth("", n.username, n.password, host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: [gotlsaflare] %s %s\r\n", event.Event, event.Name)
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", event.Message)
+	if event.RecordID != "" {
+		fmt.Fprintf(&msg, "Record ID: %s\r\n", event.RecordID)
+	}
+	if event.Error != "" {
+		fmt.Fprintf(&msg, "Error: %s\r\n", event.Error)
+	}
+
+	if err := smtp.SendMail(n.addr, auth, n.from, n.to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("error sending notification email: %v", err)
+	}
+	return nil
+}
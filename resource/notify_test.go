@@ -0,0 +1,249 @@
+package resource
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// setNotifiers installs notifiers for one test.
+func setNotifiers(t *testing.T, n ...notifier) {
+	t.Helper()
+	notifiers, notifyOn = n, nil
+	t.Cleanup(func() { notifiers, notifyOn = nil, nil })
+}
+
+// newWebhookServer records the bodies posted to it.
+func newWebhookServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body strings.Builder
+		bufio.NewReader(r.Body).WriteTo(&body)
+		mu.Lock()
+		bodies = append(bodies, body.String())
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+// newSMTPServer is a minimal SMTP stand-in returning the DATA of each
+// message it receives.
+func newSMTPServer(t *testing.T) (string, func() []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start SMTP stand-in: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	var messages []string
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				conn.Write([]byte("220 localhost ESMTP\r\n"))
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+						conn.Write([]byte("250 localhost\r\n"))
+					case cmd == "DATA":
+						conn.Write([]byte("354 go ahead\r\n"))
+						var data strings.Builder
+						for {
+							line, err := reader.ReadString('\n')
+							if err != nil || line == ".\r\n" {
+								break
+							}
+							data.WriteString(line)
+						}
+						mu.Lock()
+						messages = append(messages, data.String())
+						mu.Unlock()
+						conn.Write([]byte("250 OK\r\n"))
+					case cmd == "QUIT":
+						conn.Write([]byte("221 bye\r\n"))
+						return
+					default:
+						conn.Write([]byte("250 OK\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), messages...)
+	}
+}
+
+func TestWebhookAndSlackNotifiers(t *testing.T) {
+	webhook, webhookBodies := newWebhookServer(t)
+	slack, slackBodies := newWebhookServer(t)
+	setNotifiers(t, webhookNotifier{url: webhook.URL}, slackNotifier{url: slack.URL})
+
+	notify(eventRolloverFinalized, "_25._tcp.mail.example.com", "record-2", "Rollover finalized, old TLSA record deleted", nil)
+
+	bodies := webhookBodies()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 webhook call, got %d", len(bodies))
+	}
+	var event notifyEvent
+	if err := json.Unmarshal([]byte(bodies[0]), &event); err != nil {
+		t.Fatalf("Expected JSON event, got %q: %v", bodies[0], err)
+	}
+	if event.Event != eventRolloverFinalized || event.Name != "_25._tcp.mail.example.com" || event.RecordID != "record-2" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	var message map[string]string
+	if err := json.Unmarshal([]byte(slackBodies()[0]), &message); err != nil {
+		t.Fatalf("Expected Slack JSON payload: %v", err)
+	}
+	if !strings.Contains(message["text"], "rollover_finalized _25._tcp.mail.example.com") {
+		t.Errorf("Unexpected Slack text: %s", message["text"])
+	}
+}
+
+func TestNotify_FiltersEvents(t *testing.T) {
+	webhook, bodies := newWebhookServer(t)
+	setNotifiers(t, webhookNotifier{url: webhook.URL})
+	notifyOn = []string{eventFailed}
+
+	notify(eventPublished, "_25._tcp.mail.example.com", "record-1", "Created TLSA record", nil)
+	if n := len(bodies()); n != 0 {
+		t.Errorf("Expected published event to be filtered, got %d calls", n)
+	}
+}
+
+func TestExecNotifier(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "event.out")
+	script := filepath.Join(dir, "notify.sh")
+	content := "#!/bin/sh\necho \"$GOTLSAFLARE_EVENT $GOTLSAFLARE_NAME\" > " + out + "\ncat >> " + out + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	event := notifyEvent{Event: eventOldRecordPreserved, Name: "_25._tcp.mail.example.com", Message: "Old TLSA record preserved", Time: time.Now()}
+	if err := (execNotifier{path: script}).notify(context.Background(), event); err != nil {
+		t.Fatalf("notify() error = %v", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Script did not run: %v", err)
+	}
+	if !strings.HasPrefix(string(got), "old_record_preserved _25._tcp.mail.example.com\n") || !strings.Contains(string(got), `"event":"old_record_preserved"`) {
+		t.Errorf("Unexpected script output: %s", got)
+	}
+
+	if err := (execNotifier{path: filepath.Join(dir, "missing.sh")}).notify(context.Background(), event); err == nil {
+		t.Error("Expected error for missing script")
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, messages := newSMTPServer(t)
+
+	n := smtpNotifier{addr: addr, from: "gotlsaflare@example.com", to: []string{"ops@example.com"}}
+	event := notifyEvent{Event: eventPropagationFailed, Name: "_25._tcp.mail.example.com", Message: "DNS propagation check failed", Error: "timeout", Time: time.Now()}
+	if err := n.notify(context.Background(), event); err != nil {
+		t.Fatalf("notify() error = %v", err)
+	}
+
+	got := messages()
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(got))
+	}
+	for _, want := range []string{"Subject: [gotlsaflare] propagation_failed _25._tcp.mail.example.com", "To: ops@example.com", "Error: timeout"} {
+		if !strings.Contains(got[0], want) {
+			t.Errorf("Expected email to contain %q, got:\n%s", want, got[0])
+		}
+	}
+}
+
+func TestConfigureNotifiers(t *testing.T) {
+	t.Cleanup(func() { notifiers, notifyOn = nil, nil })
+
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringSlice("notify-webhook", nil, "")
+		cmd.Flags().StringSlice("notify-slack", nil, "")
+		cmd.Flags().StringSlice("notify-exec", nil, "")
+		cmd.Flags().StringSlice("notify-email", nil, "")
+		cmd.Flags().String("smtp-server", "", "")
+		cmd.Flags().String("smtp-from", "", "")
+		cmd.Flags().String("smtp-username", "", "")
+		cmd.Flags().StringSlice("notify-on", nil, "")
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+		return cmd
+	}
+
+	if err := configureNotifiers(newCmd("--notify-webhook", "http://a", "--notify-slack", "http://b", "--notify-exec", "/bin/true")); err != nil {
+		t.Fatalf("configureNotifiers() error = %v", err)
+	}
+	if len(notifiers) != 3 {
+		t.Errorf("Expected 3 notifiers, got %d", len(notifiers))
+	}
+
+	if err := configureNotifiers(newCmd("--notify-email", "ops@example.com")); err == nil {
+		t.Error("Expected error for --notify-email without SMTP server")
+	}
+	if err := configureNotifiers(newCmd("--notify-on", "sometimes")); err == nil {
+		t.Error("Expected error for unknown event")
+	}
+}
+
+func TestRunCreate_NotifiesPublish(t *testing.T) {
+	newFakeCloudflare(t, "example.com")
+	webhook, bodies := newWebhookServer(t)
+	setNotifiers(t, webhookNotifier{url: webhook.URL})
+
+	opts := syncTestOptions(t)
+	opts.Services = opts.Services[:1]
+	if _, err := runCreateAll([]tlsaOptions{opts}); err != nil {
+		t.Fatalf("runCreateAll() error = %v", err)
+	}
+	// The second create fails on the existing record
+	runCreateAll([]tlsaOptions{opts})
+
+	got := bodies()
+	if len(got) != 2 || !strings.Contains(got[0], `"event":"published"`) || !strings.Contains(got[1], `"event":"failed"`) {
+		t.Errorf("Expected published then failed events, got %v", got)
+	}
+}
@@ -0,0 +1,378 @@
+package resource
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// tlsaOptions holds the record selection flags shared by every command that
+// publishes TLSA records (see addCommonFlags in cmd/create.go).
+type tlsaOptions struct {
+	URL          string
+	Subdomain    string
+	Cert         string
+	Services     []tlsaService
+	DaneEE       bool
+	DaneTA       bool
+	Selector     int
+	MatchingType int
+	TTL          int
+	Comment      string
+	Tags         []string
+}
+
+// tlsaService is the port and protocol part of a TLSA owner name.
+type tlsaService struct {
+	Port     string
+	Protocol string
+}
+
+func (s tlsaService) prefix() string {
+	return "_" + s.Port + "._" + s.Protocol + "."
+}
+
+// loadTLSAOptions returns the records to manage, read from --config when
+// given, found from the MX and SRV records with --from-mx, and from the
+// per-invocation flags otherwise. Hosts that are CNAMEs are handled as
+// --cname asks.
+func loadTLSAOptions(cmd *cobra.Command) ([]tlsaOptions, error) {
+	var all []tlsaOptions
+	var err error
+	if f := cmd.Flags().Lookup("from-mx"); f != nil && f.Value.String() != "" {
+		all, err = discoverTLSAOptions(cmd, f.Value.String())
+	} else if f := cmd.Flags().Lookup("config"); f != nil && f.Value.String() != "" {
+		var config *Config
+		config, err = loadConfig(f.Value.String())
+		if err == nil {
+			all, err = config.options()
+		}
+	} else {
+		var opts tlsaOptions
+		opts, err = parseTLSAOptions(cmd)
+		all = []tlsaOptions{opts}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		if err := applyRecordFlags(cmd, &all[i]); err != nil {
+			return nil, err
+		}
+	}
+	return followCNAMEs(cmd, all)
+}
+
+// applyRecordFlags sets the TTL, comment template and tags given with
+// --ttl, --comment and --tag, overriding the config file. Commands without
+// these flags keep the defaults.
+func applyRecordFlags(cmd *cobra.Command, opts *tlsaOptions) error {
+	if f := cmd.Flags().Lookup("ttl"); f != nil && f.Changed {
+		ttl, err := parseTTL(f.Value.String())
+		if err != nil {
+			return err
+		}
+		opts.TTL = ttl
+	}
+	if f := cmd.Flags().Lookup("comment"); f != nil && f.Changed {
+		opts.Comment = f.Value.String()
+	}
+	if cmd.Flags().Lookup("tag") != nil && cmd.Flags().Changed("tag") {
+		tags, err := cmd.Flags().GetStringSlice("tag")
+		if err != nil {
+			return err
+		}
+		opts.Tags = tags
+	}
+	return opts.validateRecordSettings()
+}
+
+// parseTTL accepts a TTL in seconds or "auto", which Cloudflare stores as 1.
+func parseTTL(value string) (int, error) {
+	if strings.EqualFold(value, "auto") {
+		return ttlAuto, nil
+	}
+	ttl, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TTL %q, must be \"auto\" or a number of seconds", value)
+	}
+	return ttl, nil
+}
+
+func parseTLSAOptions(cmd *cobra.Command) (tlsaOptions, error) {
+	opts, err := parseTLSASelection(cmd)
+	if err != nil {
+		return opts, err
+	}
+
+	if !cmd.Flags().Changed("cert") {
+		return opts, fmt.Errorf("required flag \"cert\" not set (or use --config)")
+	}
+
+	if err := opts.validate(); err != nil {
+		return opts, err
+	}
+	warnCertCoverage(opts)
+	return opts, nil
+}
+
+// parseTLSASelection reads the host, port and usage flags without requiring
+// a certificate or validating the result.
+func parseTLSASelection(cmd *cobra.Command) (tlsaOptions, error) {
+	var opts tlsaOptions
+	var err error
+
+	opts.URL, err = cmd.Flags().GetString("url")
+	if err != nil {
+		return opts, err
+	}
+	opts.Subdomain, err = cmd.Flags().GetString("subdomain")
+	if err != nil {
+		return opts, err
+	}
+	opts.Cert, err = cmd.Flags().GetString("cert")
+	if err != nil {
+		return opts, err
+	}
+	tcp25, err := cmd.Flags().GetBool("tcp25")
+	if err != nil {
+		return opts, err
+	}
+	tcp465, err := cmd.Flags().GetBool("tcp465")
+	if err != nil {
+		return opts, err
+	}
+	tcp587, err := cmd.Flags().GetBool("tcp587")
+	if err != nil {
+		return opts, err
+	}
+
+	tcpPort, err := cmd.Flags().GetInt("tcp-port")
+	if err != nil {
+		return opts, err
+	}
+
+	opts.DaneEE, err = cmd.Flags().GetBool("dane-ee")
+	if err != nil {
+		return opts, err
+	}
+
+	noDaneEE, err := cmd.Flags().GetBool("no-dane-ee")
+	if err != nil {
+		return opts, err
+	}
+
+	opts.DaneTA, err = cmd.Flags().GetBool("dane-ta")
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Selector, err = cmd.Flags().GetInt("selector")
+	if err != nil {
+		return opts, err
+	}
+
+	opts.MatchingType, err = cmd.Flags().GetInt("matching-type")
+	if err != nil {
+		return opts, err
+	}
+
+	// Handle the case where both --dane-ee and --no-dane-ee are specified
+	if noDaneEE {
+		opts.DaneEE = false
+	}
+
+	// Collect all ports to process
+	var ports []string
+	if tcpPort != 0 {
+		ports = append(ports, strconv.Itoa(tcpPort))
+	}
+	if tcp25 {
+		ports = append(ports, "25")
+	}
+	if tcp465 {
+		ports = append(ports, "465")
+	}
+	if tcp587 {
+		ports = append(ports, "587")
+	}
+	for _, port := range ports {
+		opts.Services = append(opts.Services, tlsaService{Port: port, Protocol: "tcp"})
+	}
+
+	// --from-mx finds the hosts and ports instead
+	if f := cmd.Flags().Lookup("from-mx"); f != nil && f.Value.String() != "" {
+		return opts, nil
+	}
+	for _, name := range []string{"url", "subdomain"} {
+		if !cmd.Flags().Changed(name) {
+			return opts, fmt.Errorf("required flag \"%s\" not set (or use --config)", name)
+		}
+	}
+
+	return opts, nil
+}
+
+func (o tlsaOptions) validate() error {
+	if err := validateSubdomain(o.Subdomain); err != nil {
+		return err
+	}
+
+	// Ensure at least one of DANE-EE or DANE-TA is enabled
+	if !o.DaneEE && !o.DaneTA {
+		return fmt.Errorf("at least one of DANE-EE or DANE-TA must be enabled")
+	}
+
+	// Validate matching type
+	if o.MatchingType != 1 && o.MatchingType != 2 {
+		return fmt.Errorf("matching type must be either 1 (SHA2-256) or 2 (SHA2-512)")
+	}
+
+	// Validate that at least one port is specified
+	if len(o.Services) == 0 {
+		return fmt.Errorf("no ports specified. Please specify at least one port using --tcp-port, --tcp25, --tcp465, or --tcp587")
+	}
+
+	return o.validateRecordSettings()
+}
+
+// validateRecordSettings checks the TTL, comment template and tags.
+func (o tlsaOptions) validateRecordSettings() error {
+	if o.TTL != 0 && o.TTL != ttlAuto && (o.TTL < 60 || o.TTL > 86400) {
+		return fmt.Errorf("TTL %d must be auto (1) or between 60 and 86400 seconds", o.TTL)
+	}
+	if _, err := parseCommentTemplate(o.Comment); err != nil {
+		return err
+	}
+	for _, tag := range o.Tags {
+		if err := validateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectors returns the selector to use for DANE-EE and DANE-TA records.
+func (o tlsaOptions) selectors() (int, int) {
+	// If selector is not explicitly set (-1), use defaults
+	if o.Selector == -1 {
+		return 1, 0 // SPKI(1) for DANE-EE, Cert(0) for DANE-TA
+	}
+	return o.Selector, o.Selector
+}
+
+// record generates the Cloudflare request for one record of the options.
+func (o tlsaOptions) record(svc tlsaService, action string, usage int, selector int, certificate string) JSONRequest {
+	req := buildCloudflareReq(svc.Port, svc.Protocol, o.Subdomain, action, usage, selector, o.MatchingType, certificate)
+	if o.TTL != 0 {
+		req.Ttl = o.TTL
+	}
+	req.Comment = o.comment(action, req.Name+"."+o.URL, usage)
+	req.Tags = o.Tags
+	return req
+}
+
+// records returns every record the options describe, DANE-EE before DANE-TA
+// for each service. The certificate is read once, and a file that cannot be
+// read or parsed is an error rather than an exit, since it may be mid-rewrite.
+func (o tlsaOptions) records(action string) ([]JSONRequest, error) {
+	eeHash, caHash, err := o.hashes()
+	if err != nil {
+		return nil, err
+	}
+	eeSel, taSel := o.selectors()
+	var records []JSONRequest
+	for _, svc := range o.Services {
+		if o.DaneEE {
+			records = append(records, o.record(svc, action, 3, eeSel, eeHash))
+		}
+		if o.DaneTA {
+			records = append(records, o.record(svc, action, 2, taSel, caHash))
+		}
+	}
+	return records, nil
+}
+
+// host returns the fully qualified host name the TLSA records are published for.
+func (o tlsaOptions) host() string {
+	return hostName(o.Subdomain, o.URL)
+}
+
+// apexSubdomain selects the zone apex, as in zone files.
+const apexSubdomain = "@"
+
+// isApex reports whether subdomain names the zone apex.
+func isApex(subdomain string) bool {
+	return subdomain == "" || subdomain == apexSubdomain
+}
+
+// hostName returns the fully qualified name of subdomain in zone.
+func hostName(subdomain, zone string) string {
+	if isApex(subdomain) {
+		return strings.ToLower(zone)
+	}
+	return strings.ToLower(subdomain + "." + zone)
+}
+
+// validateSubdomain accepts the apex and one or more labels. Wildcards are
+// refused: a client looks up the TLSA records of the host it connects to, so
+// a record at _25._tcp.*.example.com is never used.
+func validateSubdomain(subdomain string) error {
+	if isApex(subdomain) {
+		return nil
+	}
+	if strings.Contains(subdomain, "*") {
+		return fmt.Errorf("subdomain %q is a wildcard, TLSA records must be published for each host the certificate serves", subdomain)
+	}
+	for _, label := range strings.Split(subdomain, ".") {
+		if label == "" {
+			return fmt.Errorf("subdomain %q has an empty label", subdomain)
+		}
+	}
+	return nil
+}
+
+// warnCertCoverage logs when DANE-TA is published and the certificate does
+// not name the host, which fails DANE-TA validation; DANE-EE ignores the
+// names. It also logs when the certificate covers the host only through a
+// wildcard, since every other host it serves needs TLSA records of its own.
+// An unreadable certificate is left for the hash computation to report.
+func warnCertCoverage(o tlsaOptions) {
+	cert, err := leafCertificate(o.Cert)
+	if err != nil {
+		return
+	}
+	host := o.host()
+	if err := cert.VerifyHostname(host); err != nil {
+		if o.DaneTA {
+			slog.Warn("Certificate does not cover host, DANE-TA validation will fail", "host", host, "cert", o.Cert, "names", cert.DNSNames)
+		}
+		return
+	}
+	if !slices.ContainsFunc(cert.DNSNames, func(name string) bool { return strings.EqualFold(name, host) }) {
+		slog.Info("Wildcard certificate covers host, publish TLSA records for every other host it serves", "host", host, "names", cert.DNSNames)
+	}
+}
+
+// hashes computes the DANE-EE and DANE-TA certificate association data the
+// options would publish, returning an error rather than exiting.
+func (o tlsaOptions) hashes() (string, string, error) {
+	eeSel, taSel := o.selectors()
+	eeHash, _, err := computeHash(o.Cert, eeSel, o.MatchingType)
+	if err != nil {
+		return "", "", err
+	}
+	_, caHash, err := computeHash(o.Cert, taSel, o.MatchingType)
+	if err != nil {
+		return "", "", err
+	}
+	if o.DaneTA && caHash == "" {
+		return "", "", fmt.Errorf("no CA certificate found in %s for DANE-TA", o.Cert)
+	}
+	return eeHash, caHash, nil
+}
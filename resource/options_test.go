@@ -0,0 +1,268 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParseTLSAOptions(t *testing.T) {
+	cmd := &cobra.Command{}
+	addCreateFlags(cmd)
+
+	err := cmd.ParseFlags([]string{
+		"--url", "example.com",
+		"--subdomain", "mail",
+		"--cert", "cert.pem",
+		"--tcp-port", "443",
+		"--tcp25",
+		"--dane-ta",
+		"--no-dane-ee",
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	opts, err := parseTLSAOptions(cmd)
+	if err != nil {
+		t.Fatalf("parseTLSAOptions() error = %v", err)
+	}
+
+	var prefixes []string
+	for _, svc := range opts.Services {
+		prefixes = append(prefixes, svc.prefix())
+	}
+	if strings.Join(prefixes, ",") != "_443._tcp.,_25._tcp." {
+		t.Errorf("Expected services _443._tcp.,_25._tcp., got %v", prefixes)
+	}
+	if opts.DaneEE || !opts.DaneTA {
+		t.Errorf("Expected DANE-TA only, got DaneEE=%v DaneTA=%v", opts.DaneEE, opts.DaneTA)
+	}
+}
+
+func TestTLSAOptions_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    tlsaOptions
+		wantErr string
+	}{
+		{"Valid", tlsaOptions{Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, MatchingType: 1}, ""},
+		{"NoUsage", tlsaOptions{Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, MatchingType: 1}, "DANE-EE or DANE-TA"},
+		{"BadMatchingType", tlsaOptions{Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, MatchingType: 3}, "matching type"},
+		{"NoPorts", tlsaOptions{DaneEE: true, MatchingType: 1}, "no ports specified"},
+		{"Apex", tlsaOptions{Subdomain: "@", Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, MatchingType: 1}, ""},
+		{"MultiLevel", tlsaOptions{Subdomain: "mx1.mail", Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, MatchingType: 1}, ""},
+		{"Wildcard", tlsaOptions{Subdomain: "*.mail", Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, MatchingType: 1}, "is a wildcard"},
+		{"EmptyLabel", tlsaOptions{Subdomain: "mx1..mail", Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, MatchingType: 1}, "empty label"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("validate() error = %v, want containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSAOptions_Selectors(t *testing.T) {
+	ee, ta := tlsaOptions{Selector: -1}.selectors()
+	if ee != 1 || ta != 0 {
+		t.Errorf("Expected default selectors 1/0, got %d/%d", ee, ta)
+	}
+
+	ee, ta = tlsaOptions{Selector: 0}.selectors()
+	if ee != 0 || ta != 0 {
+		t.Errorf("Expected explicit selectors 0/0, got %d/%d", ee, ta)
+	}
+}
+
+func TestParseTLSAOptions_RequiredFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	addCreateFlags(cmd)
+
+	if err := cmd.ParseFlags([]string{"--url", "example.com", "--tcp25"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	_, err := parseTLSAOptions(cmd)
+	if err == nil || !strings.Contains(err.Error(), "subdomain") {
+		t.Errorf("Expected missing subdomain error, got %v", err)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"auto", ttlAuto, false},
+		{"AUTO", ttlAuto, false},
+		{"300", 300, false},
+		{"five", 0, true},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseTTL(tc.value)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("parseTTL(%q) = %d, %v, want %d (error %v)", tc.value, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestApplyRecordFlags(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("ttl", "3600", "")
+		cmd.Flags().String("comment", "", "")
+		cmd.Flags().StringSlice("tag", nil, "")
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+		return cmd
+	}
+
+	opts := syncTestOptions(t)
+	opts.TTL = 600
+	if err := applyRecordFlags(newCmd(), &opts); err != nil {
+		t.Fatalf("applyRecordFlags() error = %v", err)
+	}
+	if opts.TTL != 600 {
+		t.Errorf("Expected unset --ttl to keep TTL 600, got %d", opts.TTL)
+	}
+
+	if err := applyRecordFlags(newCmd("--ttl", "auto", "--comment", "CHG-1 {{.Action}}", "--tag", "team:mail,dane"), &opts); err != nil {
+		t.Fatalf("applyRecordFlags() error = %v", err)
+	}
+	record := mustRecords(t, opts, "Created")[0]
+	if record.Ttl != ttlAuto || strings.Join(record.Tags, ",") != "team:mail,dane" || !strings.HasPrefix(record.Comment, "CHG-1 Created") {
+		t.Errorf("Unexpected record settings: ttl=%d tags=%v comment=%q", record.Ttl, record.Tags, record.Comment)
+	}
+
+	for _, args := range [][]string{{"--ttl", "30"}, {"--comment", "{{.Ticket}}"}, {"--tag", "bad tag"}} {
+		opts := syncTestOptions(t)
+		if err := applyRecordFlags(newCmd(args...), &opts); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+func TestTLSAOptions_ApexAndMultiLevelNames(t *testing.T) {
+	cert := generateTestCertForReq(t)
+	testCases := []struct {
+		subdomain, host, name string
+	}{
+		{"@", "example.com", "_25._tcp.example.com"},
+		{"", "example.com", "_25._tcp.example.com"},
+		{"mx1.mail", "mx1.mail.example.com", "_25._tcp.mx1.mail.example.com"},
+	}
+
+	for _, tc := range testCases {
+		opts := tlsaOptions{URL: "example.com", Subdomain: tc.subdomain, Cert: cert, Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, Selector: -1, MatchingType: 1}
+		if opts.host() != tc.host {
+			t.Errorf("host() for %q = %s, want %s", tc.subdomain, opts.host(), tc.host)
+		}
+		record := mustRecords(t, opts, "Created")[0]
+		if name := record.Name + "." + opts.URL; name != tc.name {
+			t.Errorf("Record name for %q = %s, want %s", tc.subdomain, name, tc.name)
+		}
+	}
+}
+
+func mustRecords(t *testing.T, opts tlsaOptions, action string) []JSONRequest {
+	t.Helper()
+
+	records, err := opts.records(action)
+	if err != nil {
+		t.Fatalf("records() error = %v", err)
+	}
+	return records
+}
+
+func TestTLSAOptions_RecordsUnreadableCert(t *testing.T) {
+	cert := filepath.Join(t.TempDir(), "cert.pem")
+	// A certificate truncated mid-rewrite must be an error, not an exit
+	if err := os.WriteFile(cert, []byte("-----BEGIN CERTIFICATE-----\nMIIB"), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	opts := tlsaOptions{URL: "example.com", Subdomain: "mail", Cert: cert, Services: []tlsaService{{Port: "25", Protocol: "tcp"}}, DaneEE: true, Selector: -1, MatchingType: 1}
+
+	if _, err := opts.records("Created"); err == nil {
+		t.Error("Expected error for truncated certificate")
+	}
+	if _, err := updateJobs(opts, false); err == nil {
+		t.Error("Expected updateJobs error for truncated certificate")
+	}
+}
+
+func writeTestCertNames(t *testing.T, names ...string) string {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     names,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	return path
+}
+
+func TestWarnCertCoverage(t *testing.T) {
+	cert := writeTestCertNames(t, "example.com", "*.example.com")
+	testCases := []struct {
+		subdomain string
+		daneTA    bool
+		want      string
+	}{
+		{"mail", false, "Wildcard certificate covers host"},
+		{"@", false, ""},
+		// DANE-EE ignores the certificate names
+		{"mx1.mail", false, ""},
+		{"mx1.mail", true, "Certificate does not cover host"},
+	}
+
+	original := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(original) })
+	for _, tc := range testCases {
+		var logs bytes.Buffer
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+		warnCertCoverage(tlsaOptions{URL: "example.com", Subdomain: tc.subdomain, Cert: cert, DaneEE: true, DaneTA: tc.daneTA})
+		if tc.want == "" && logs.Len() != 0 || !strings.Contains(logs.String(), tc.want) {
+			t.Errorf("Subdomain %q: expected %q, got %q", tc.subdomain, tc.want, logs.String())
+		}
+	}
+}
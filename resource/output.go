@@ -0,0 +1,72 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// actionRollover marks a record replaced by a rolling update.
+const actionRollover = "rollover"
+
+// recordResult is the outcome of one record in a create or update run.
+type recordResult struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// resultDocument is written by --output json as the only thing on stdout.
+type resultDocument struct {
+	Command string         `json:"command"`
+	Success bool           `json:"success"`
+	Results []recordResult `json:"results"`
+	Error   string         `json:"error,omitempty"`
+}
+
+func newRecordResult(name, action, id string, err error) recordResult {
+	result := recordResult{Name: name, Action: action, ID: id}
+	if err != nil {
+		result.Error = Redact(err.Error())
+	}
+	return result
+}
+
+// outputFormat returns the --output value, text when the flag is absent.
+func outputFormat(cmd *cobra.Command) (string, error) {
+	flag := cmd.Flags().Lookup("output")
+	if flag == nil {
+		return "text", nil
+	}
+	switch flag.Value.String() {
+	case "text", "json":
+		return flag.Value.String(), nil
+	}
+	return "", fmt.Errorf("invalid output format %q, must be text or json", flag.Value.String())
+}
+
+// writeResults writes the result document when output is json, and returns
+// runErr so callers can finish with it.
+func writeResults(w io.Writer, output, command string, results []recordResult, runErr error) error {
+	if output != "json" {
+		return runErr
+	}
+
+	doc := resultDocument{Command: command, Success: runErr == nil, Results: results}
+	if doc.Results == nil {
+		doc.Results = []recordResult{}
+	}
+	if runErr != nil {
+		doc.Error = Redact(runErr.Error())
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("error writing results: %v", err)
+	}
+	return runErr
+}
@@ -0,0 +1,89 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWriteResults(t *testing.T) {
+	var buf bytes.Buffer
+	results := []recordResult{
+		newRecordResult("_25._tcp.mail.example.com", actionCreate, "record-1", nil),
+		newRecordResult("_587._tcp.mail.example.com", actionCreate, "", errors.New("TLSA record already exists")),
+	}
+
+	err := writeResults(&buf, "json", "create", results, errors.New("TLSA record already exists"))
+	if err == nil {
+		t.Error("Expected the run error to be returned")
+	}
+
+	var doc resultDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected a JSON document, got %q: %v", buf.String(), err)
+	}
+	if doc.Command != "create" || doc.Success || len(doc.Results) != 2 {
+		t.Errorf("Unexpected document: %+v", doc)
+	}
+	if doc.Results[0].ID != "record-1" || doc.Results[1].Error == "" {
+		t.Errorf("Unexpected results: %+v", doc.Results)
+	}
+
+	buf.Reset()
+	if err := writeResults(&buf, "text", "create", results, nil); err != nil || buf.Len() != 0 {
+		t.Errorf("Expected nothing written for text output, got %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	writeResults(&buf, "json", "update", nil, nil)
+	if !bytes.Contains(buf.Bytes(), []byte(`"results": []`)) {
+		t.Errorf("Expected empty results list, got %s", buf.String())
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	cmd := &cobra.Command{}
+	if output, err := outputFormat(cmd); output != "text" || err != nil {
+		t.Errorf("Expected text without the flag, got %q, %v", output, err)
+	}
+
+	cmd.Flags().String("output", "text", "")
+	cmd.Flags().Set("output", "yaml")
+	if _, err := outputFormat(cmd); err == nil {
+		t.Error("Expected error for unsupported output format")
+	}
+}
+
+func TestRunCreateAndUpdate_Results(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+
+	results, err := runCreateAll([]tlsaOptions{opts})
+	if err != nil {
+		t.Fatalf("runCreateAll() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Name != "_25._tcp.mail.example.com" || results[0].ID == "" || results[0].Action != actionCreate {
+		t.Errorf("Unexpected create results: %+v", results)
+	}
+
+	// Creating again must fail on the existing record
+	results, err = runCreateAll([]tlsaOptions{opts})
+	if err == nil || len(results) != 1 || results[0].Error == "" {
+		t.Errorf("Expected existing record error in results, got %+v, %v", results, err)
+	}
+	if len(f.zoneRecords("zone-1")) != 2 {
+		t.Errorf("Expected no duplicate records, got %d", len(f.zoneRecords("zone-1")))
+	}
+
+	results, err = runUpdateAll(context.Background(), []tlsaOptions{opts}, false, false, 1)
+	if err != nil {
+		t.Fatalf("runUpdateAll() error = %v", err)
+	}
+	if len(results) != 2 || results[1].Action != actionUpdate || results[1].ID == "" {
+		t.Errorf("Unexpected update results: %+v", results)
+	}
+}
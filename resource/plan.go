@@ -0,0 +1,377 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// savedPlan is the file written by --save-plan and executed by apply.
+type savedPlan struct {
+	Version   int            `json:"version"`
+	Command   string         `json:"command"`
+	Generated time.Time      `json:"generated"`
+	Changes   []recordChange `json:"changes"`
+}
+
+const planVersion = 1
+
+// providerSnapshot holds the zones and TLSA records read from the provider
+// during one run, so the zones and the records of each zone are only listed
+// once. Writes made through the snapshot keep it current, and a failed write
+// drops the records of its zone so they are listed again. When journal is
+// set, every write that took effect is recorded in it. It is safe for
+// concurrent use.
+type providerSnapshot struct {
+	provider dnsProvider
+	zones    []Zone
+	mu       sync.Mutex
+	records  map[string][]DNSRecord
+	journal  *journal
+}
+
+func newProviderSnapshot(auth cloudflareAuth) (*providerSnapshot, error) {
+	provider := providerFor(auth)
+	zones, err := provider.zones()
+	if err != nil {
+		return nil, err
+	}
+	return &providerSnapshot{provider: provider, zones: zones, records: make(map[string][]DNSRecord)}, nil
+}
+
+func (s *providerSnapshot) zoneFor(name string) (Zone, error) {
+	zone, ok := findZone(s.zones, name)
+	if !ok {
+		return zone, fmt.Errorf("no matching zone found for %s", name)
+	}
+	return zone, nil
+}
+
+func (s *providerSnapshot) tlsaRecords(zoneID string) ([]DNSRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if records, ok := s.records[zoneID]; ok {
+		return slices.Clone(records), nil
+	}
+	records, err := s.provider.tlsaRecords(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	s.records[zoneID] = records
+	return slices.Clone(records), nil
+}
+
+// batch applies the changes to one zone. Updated records are matched to the
+// cache by the ID they were requested with, since a provider may give the
+// new contents a new ID.
+func (s *providerSnapshot) batch(zoneID string, batch recordBatch) (*batchResult, error) {
+	var before []DNSRecord
+	if s.journal != nil && len(batch.Deletes)+len(batch.Puts) > 0 {
+		before, _ = s.tlsaRecords(zoneID)
+	}
+	result, err := s.provider.apply(zoneID, batch)
+	if s.journal != nil {
+		s.journal.recordBatch(zoneID, before, batch, result)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, ok := s.records[zoneID]
+	if !ok {
+		return result, err
+	}
+	if err != nil {
+		delete(s.records, zoneID)
+		return result, err
+	}
+	for _, deleted := range batch.Deletes {
+		records = slices.DeleteFunc(records, func(r DNSRecord) bool { return r.ID == deleted.ID })
+	}
+	for i, updated := range result.Puts {
+		if j := slices.IndexFunc(records, func(r DNSRecord) bool { return r.ID == batch.Puts[i].ID }); j >= 0 {
+			records[j] = updated
+		}
+	}
+	s.records[zoneID] = append(records, result.Posts...)
+	return result, nil
+}
+
+// recordsAt returns the TLSA records at an owner name.
+func (s *providerSnapshot) recordsAt(zoneID, name string) ([]DNSRecord, error) {
+	records, err := s.tlsaRecords(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	var at []DNSRecord
+	for _, record := range records {
+		if strings.EqualFold(record.Name, name) {
+			at = append(at, record)
+		}
+	}
+	return at, nil
+}
+
+// planCreate mirrors create: every record is added, and any existing DANE-EE
+// or DANE-TA record at its name is an error.
+func planCreate(all []tlsaOptions, auth cloudflareAuth) ([]recordChange, error) {
+	snapshot, err := newProviderSnapshot(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []recordChange
+	for _, opts := range all {
+		records, err := opts.records("Created")
+		if err != nil {
+			return nil, err
+		}
+		zone, err := snapshot.zoneFor(opts.host())
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			name := strings.ToLower(record.Name + "." + opts.URL)
+			if err := checkNoTLSARecord(snapshot, zone.ID, name); err != nil {
+				return nil, err
+			}
+			next := record
+			changes = append(changes, recordChange{Action: actionCreate, ZoneID: zone.ID, Name: name, New: &next})
+		}
+	}
+	return changes, nil
+}
+
+// planUpdate mirrors update: the existing record of each usage is replaced in
+// place, or with rollover a new record is added and the old one deleted after
+// two TTL periods.
+func planUpdate(all []tlsaOptions, auth cloudflareAuth, rollover bool) ([]recordChange, error) {
+	snapshot, err := newProviderSnapshot(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []recordChange
+	for _, opts := range all {
+		records, err := opts.records("Updated")
+		if err != nil {
+			return nil, err
+		}
+		zone, err := snapshot.zoneFor(opts.host())
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			name := strings.ToLower(record.Name + "." + opts.URL)
+			existing, err := snapshot.recordsAt(zone.ID, name)
+			if err != nil {
+				return nil, err
+			}
+
+			var old *DNSRecord
+			for i := range existing {
+				if existing[i].Data.Usage == record.Data.Usage {
+					old = &existing[i]
+				}
+			}
+
+			next := record
+			switch {
+			case old == nil:
+				return nil, fmt.Errorf("could not find existing TLSA record with usage %d for %s", record.Data.Usage, name)
+			case sameTLSAData(record, *old) && sameRecordSettings(record, *old):
+				changes = append(changes, recordChange{Action: actionNoop, ZoneID: zone.ID, Name: name, Old: old})
+			case rollover && (record.Data.Usage == 3 || !opts.DaneEE):
+				changes = append(changes,
+					recordChange{Action: actionCreate, ZoneID: zone.ID, Name: name, New: &next},
+					recordChange{Action: actionDelete, ZoneID: zone.ID, Name: name, Old: old, Note: "after 2 TTL periods"})
+			default:
+				changes = append(changes, recordChange{Action: actionUpdate, ZoneID: zone.ID, Name: name, Old: old, New: &next})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// printPlan writes a Terraform-style summary of the changes.
+func printPlan(w io.Writer, changes []recordChange) {
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Action]++
+		switch change.Action {
+		case actionCreate:
+			fmt.Fprintf(w, "  # %s will be created\n", change.Name)
+			fmt.Fprintf(w, "  + TLSA %s\n", formatTLSAData(change.New.Data.Usage, change.New.Data.Selector, change.New.Data.Matchingtype, change.New.Data.Certificate))
+			fmt.Fprintf(w, "      ttl: %d\n", change.New.Ttl)
+			if len(change.New.Tags) > 0 {
+				fmt.Fprintf(w, "      tags: %s\n", strings.Join(change.New.Tags, ", "))
+			}
+		case actionUpdate:
+			// Import adopts unmanaged records by marking their comment
+			adopted := !isManaged(*change.Old) && strings.Contains(change.New.Comment, managedMarker)
+			if adopted {
+				fmt.Fprintf(w, "  # %s will be adopted\n", change.Name)
+			} else {
+				fmt.Fprintf(w, "  # %s will be updated in-place\n", change.Name)
+			}
+			fmt.Fprintf(w, "  ~ TLSA usage %d\n", change.Old.Data.Usage)
+			if adopted {
+				printField(w, "comment", change.Old.Comment, change.New.Comment)
+			}
+			printField(w, "selector", fmt.Sprint(change.Old.Data.Selector), fmt.Sprint(change.New.Data.Selector))
+			printField(w, "matching_type", fmt.Sprint(change.Old.Data.MatchingType), fmt.Sprint(change.New.Data.Matchingtype))
+			printField(w, "certificate", change.Old.Data.Certificate, change.New.Data.Certificate)
+			printField(w, "ttl", fmt.Sprint(change.Old.TTL), fmt.Sprint(change.New.Ttl))
+			printField(w, "tags", strings.Join(change.Old.Tags, ", "), strings.Join(change.New.Tags, ", "))
+		case actionDelete:
+			note := ""
+			if change.Note != "" {
+				note = " " + change.Note
+			}
+			fmt.Fprintf(w, "  # %s will be deleted%s\n", change.Name, note)
+			fmt.Fprintf(w, "  - TLSA %s\n", formatTLSAData(change.Old.Data.Usage, change.Old.Data.Selector, change.Old.Data.MatchingType, change.Old.Data.Certificate))
+		}
+	}
+
+	if counts[actionCreate]+counts[actionUpdate]+counts[actionDelete] == 0 {
+		fmt.Fprintln(w, "No changes. TLSA records match the certificates.")
+		return
+	}
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to destroy.\n",
+		counts[actionCreate], counts[actionUpdate], counts[actionDelete])
+}
+
+func printField(w io.Writer, field, old, new string) {
+	if old == new {
+		return
+	}
+	fmt.Fprintf(w, "      %s: %q -> %q\n", field, old, new)
+}
+
+// handleDryRun prints the plan when --dry-run or --save-plan is set and
+// reports whether the command should stop without mutating anything.
+func handleDryRun(cmd *cobra.Command, command string, plan func() ([]recordChange, error)) (bool, error) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	savePath, _ := cmd.Flags().GetString("save-plan")
+	if !dryRun && savePath == "" {
+		return false, nil
+	}
+
+	changes, err := plan()
+	if err != nil {
+		return true, err
+	}
+	printPlan(os.Stdout, changes)
+
+	if savePath != "" {
+		for _, change := range changes {
+			if change.Note != "" {
+				return true, fmt.Errorf("plans with deferred changes (%s) cannot be saved", change.Note)
+			}
+		}
+		if err := writePlan(savePath, command, changes); err != nil {
+			return true, err
+		}
+		slog.Info("Saved plan, run \"gotlsaflare apply\" to execute it", "path", savePath)
+	}
+	return true, nil
+}
+
+func writePlan(path, command string, changes []recordChange) error {
+	plan := savedPlan{Version: planVersion, Command: command, Generated: time.Now().UTC()}
+	for _, change := range changes {
+		if change.Action != actionNoop {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding plan: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("error writing plan: %v", err)
+	}
+	return nil
+}
+
+func readPlan(path string) (*savedPlan, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan: %v", err)
+	}
+
+	var plan savedPlan
+	if err := json.Unmarshal(content, &plan); err != nil {
+		return nil, fmt.Errorf("error parsing plan %s: %v", path, err)
+	}
+	if plan.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d", plan.Version)
+	}
+	return &plan, nil
+}
+
+func ResourceApply(cmd *cobra.Command, args []string) error {
+	plan, err := readPlan(args[0])
+	if err != nil {
+		return err
+	}
+
+	auth := cloudflareCredentials()
+	if err := verifyPlan(plan.Command, plan.Changes, auth); err != nil {
+		return err
+	}
+
+	printPlan(os.Stdout, plan.Changes)
+	if err := preflight(cmd, auth, changeNames(plan.Changes)); err != nil {
+		return err
+	}
+	return applyChanges(plan.Changes, auth)
+}
+
+// verifyPlan refuses to apply a plan whose starting point no longer matches
+// the provider, so apply executes exactly what was reviewed. Creates planned
+// by create and batch are held to the rule create enforces: no DANE-EE or
+// DANE-TA record may exist at the name.
+func verifyPlan(command string, changes []recordChange, auth cloudflareAuth) error {
+	snapshot := &providerSnapshot{provider: providerFor(auth), records: make(map[string][]DNSRecord)}
+
+	for _, change := range changes {
+		existing, err := snapshot.recordsAt(change.ZoneID, change.Name)
+		if err != nil {
+			return err
+		}
+
+		switch change.Action {
+		case actionUpdate, actionDelete:
+			found := false
+			for _, have := range existing {
+				if have.ID == change.Old.ID {
+					found = have.Data == change.Old.Data && have.TTL == change.Old.TTL
+				}
+			}
+			if !found {
+				return fmt.Errorf("plan is stale: record %s for %s changed since the plan was made", change.Old.ID, change.Name)
+			}
+		case actionCreate:
+			if command == "create" || command == "batch" {
+				if err := checkNoTLSARecord(snapshot, change.ZoneID, change.Name); err != nil {
+					return fmt.Errorf("plan is stale: %v", err)
+				}
+			}
+			for _, have := range existing {
+				if sameTLSAData(*change.New, have) {
+					return fmt.Errorf("plan is stale: %s already has TLSA %s", change.Name, formatTLSAData(have.Data.Usage, have.Data.Selector, have.Data.MatchingType, have.Data.Certificate))
+				}
+			}
+		}
+	}
+	return nil
+}
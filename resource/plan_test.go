@@ -0,0 +1,246 @@
+package resource
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPlanCreate(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+
+	changes, err := planCreate([]tlsaOptions{opts}, cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("planCreate() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionCreate] != 2 {
+		t.Errorf("Expected 2 creates, got %v", counts)
+	}
+
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "00ff", "")
+	if _, err := planCreate([]tlsaOptions{opts}, cloudflareCredentials()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected already exists error, got %v", err)
+	}
+
+	if len(f.zoneRecords("zone-1")) != 1 {
+		t.Error("Planning must not modify records")
+	}
+}
+
+func TestPlanCreate_RefusesOtherDANEUsage(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 2, 0, 1, "00ff", "")
+	opts := syncTestOptions(t)
+
+	// create refuses any DANE-TA or DANE-EE record, so a DANE-EE plan must too
+	if _, err := planCreate([]tlsaOptions{opts}, cloudflareCredentials()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected already exists error, got %v", err)
+	}
+	if _, err := runCreateAll([]tlsaOptions{opts}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected create to refuse as well, got %v", err)
+	}
+}
+
+func TestVerifyPlan_CreateRefusesOtherDANEUsage(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+
+	changes, err := planCreate([]tlsaOptions{opts}, cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("planCreate() error = %v", err)
+	}
+	if err := verifyPlan("create", changes, cloudflareCredentials()); err != nil {
+		t.Fatalf("verifyPlan() error = %v", err)
+	}
+
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 2, 0, 1, "00ff", "")
+	if err := verifyPlan("create", changes, cloudflareCredentials()); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("Expected stale plan error, got %v", err)
+	}
+	// sync may add a record next to one of another usage
+	if err := verifyPlan("sync", changes, cloudflareCredentials()); err != nil {
+		t.Errorf("verifyPlan() for sync error = %v", err)
+	}
+}
+
+func TestPlanUpdate(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	eeHash, _, err := opts.hashes()
+	if err != nil {
+		t.Fatalf("hashes() error = %v", err)
+	}
+
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, eeHash, "")
+	f.addRecord("zone-1", "_587._tcp.mail.example.com", 3, 1, 1, "00ff", "")
+
+	changes, err := planUpdate([]tlsaOptions{opts}, cloudflareCredentials(), false)
+	if err != nil {
+		t.Fatalf("planUpdate() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionNoop] != 1 || counts[actionUpdate] != 1 {
+		t.Errorf("Expected 1 noop and 1 update, got %v", counts)
+	}
+
+	changes, err = planUpdate([]tlsaOptions{opts}, cloudflareCredentials(), true)
+	if err != nil {
+		t.Fatalf("planUpdate() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionCreate] != 1 || counts[actionDelete] != 1 {
+		t.Errorf("Expected rollover create and delete, got %v", counts)
+	}
+
+	opts.Services = append(opts.Services, tlsaService{Port: "465", Protocol: "tcp"})
+	if _, err := planUpdate([]tlsaOptions{opts}, cloudflareCredentials(), false); err == nil {
+		t.Error("Expected error for missing record")
+	}
+}
+
+func TestProviderSnapshot_TracksWrites(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	old := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "aaaa", managedComment)
+
+	snapshot, err := newProviderSnapshot(cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("newProviderSnapshot() error = %v", err)
+	}
+	recordsAt := func() []DNSRecord {
+		t.Helper()
+		records, err := snapshot.recordsAt("zone-1", "_25._tcp.mail.example.com")
+		if err != nil {
+			t.Fatalf("recordsAt() error = %v", err)
+		}
+		return records
+	}
+
+	record := JSONRequest{Type: "TLSA", Name: "_25._tcp.mail", Data: Data{Usage: 3, Selector: 1, Matchingtype: 1, Certificate: "bbbb"}, Ttl: 3600}
+	created, err := snapshot.batch("zone-1", recordBatch{Posts: []JSONRequest{record}})
+	if err != nil {
+		t.Fatalf("batch() error = %v", err)
+	}
+	record.Data.Certificate = "cccc"
+	update := recordBatch{Deletes: []batchDelete{{ID: old.ID}}, Puts: []batchPut{{ID: created.Posts[0].ID, JSONRequest: record}}}
+	if _, err := snapshot.batch("zone-1", update); err != nil {
+		t.Fatalf("batch() error = %v", err)
+	}
+
+	records := recordsAt()
+	if len(records) != 1 || records[0].Data.Certificate != "cccc" {
+		t.Errorf("Expected the cache to hold the updated record only, got %+v", records)
+	}
+	if n := f.countCalls("GET /zones/zone-1/dns_records"); n != 1 {
+		t.Errorf("Expected writes to keep the cache, got %d listings", n)
+	}
+
+	// A failed write drops the cached records of the zone
+	f.mu.Lock()
+	f.readOnly = true
+	f.mu.Unlock()
+	if _, err := snapshot.batch("zone-1", recordBatch{Posts: []JSONRequest{record}}); err == nil {
+		t.Fatal("Expected batch to fail")
+	}
+	recordsAt()
+	if n := f.countCalls("GET /zones/zone-1/dns_records"); n != 2 {
+		t.Errorf("Expected the zone to be listed again after a failed write, got %d listings", n)
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	var old DNSRecord
+	old.ID = "record-1"
+	old.TTL = 3600
+	old.Data.Usage = 3
+	old.Data.Selector = 1
+	old.Data.MatchingType = 1
+	old.Data.Certificate = "aaaa"
+
+	next := JSONRequest{Type: "TLSA", Name: "_25._tcp.mail", Ttl: 300, Data: Data{Usage: 3, Selector: 1, Matchingtype: 1, Certificate: "bbbb"}}
+
+	var buf bytes.Buffer
+	printPlan(&buf, []recordChange{
+		{Action: actionCreate, Name: "_465._tcp.mail.example.com", New: &next},
+		{Action: actionUpdate, Name: "_25._tcp.mail.example.com", Old: &old, New: &next},
+		{Action: actionDelete, Name: "_587._tcp.mail.example.com", Old: &old},
+	})
+
+	output := buf.String()
+	expected := []string{
+		"# _465._tcp.mail.example.com will be created",
+		"+ TLSA 3 1 1 bbbb",
+		"# _25._tcp.mail.example.com will be updated in-place",
+		`certificate: "aaaa" -> "bbbb"`,
+		`ttl: "3600" -> "300"`,
+		"- TLSA 3 1 1 aaaa",
+		"Plan: 1 to add, 1 to change, 1 to destroy.",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("Expected plan output to contain %q, got:\n%s", line, output)
+		}
+	}
+	if strings.Contains(output, "selector:") {
+		t.Errorf("Unchanged fields should not be shown, got:\n%s", output)
+	}
+
+	buf.Reset()
+	printPlan(&buf, nil)
+	if !strings.Contains(buf.String(), "No changes") {
+		t.Errorf("Expected no changes message, got: %s", buf.String())
+	}
+}
+
+func TestSavePlanAndApply(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-1", "_587._tcp.mail.example.com", 3, 1, 1, "00ff", "Created by GoTLSAFlare - 2024-01-01 00:00:00")
+	opts := syncTestOptions(t)
+	planPath := filepath.Join(t.TempDir(), "tlsa.plan")
+
+	cmd := &cobra.Command{}
+	addCreateFlags(cmd)
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().Bool("prune", false, "")
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.Flags().String("save-plan", "", "")
+	err := cmd.ParseFlags([]string{
+		"--url", opts.URL,
+		"--subdomain", opts.Subdomain,
+		"--cert", opts.Cert,
+		"--tcp25", "--tcp587",
+		"--save-plan", planPath,
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if err := ResourceSync(cmd, nil); err != nil {
+		t.Fatalf("ResourceSync() with --save-plan error = %v", err)
+	}
+	if n := f.countCalls("PUT") + f.countCalls("POST") + f.countCalls("DELETE"); n != 0 {
+		t.Fatalf("Saving a plan must not mutate records, got %d calls", n)
+	}
+
+	plan, err := readPlan(planPath)
+	if err != nil {
+		t.Fatalf("readPlan() error = %v", err)
+	}
+	if plan.Command != "sync" || len(plan.Changes) != 2 {
+		t.Fatalf("Unexpected plan: %+v", plan)
+	}
+
+	if err := ResourceApply(&cobra.Command{}, []string{planPath}); err != nil {
+		t.Fatalf("ResourceApply() error = %v", err)
+	}
+	// One batch with both writes plus the preflight write probe
+	if n := f.countCalls("POST") + f.countCalls("PUT"); n != 2 {
+		t.Errorf("Expected 1 batch and 1 probe from apply, got %d", n)
+	}
+
+	// Applying the same plan again must be refused as stale
+	if err := ResourceApply(&cobra.Command{}, []string{planPath}); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("Expected stale plan error, got %v", err)
+	}
+}
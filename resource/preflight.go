@@ -0,0 +1,155 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+)
+
+// tokenInfo is the result of Cloudflare's token verify endpoint.
+type tokenInfo struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	ExpiresOn string `json:"expires_on"`
+}
+
+// verifyToken checks that the credential is accepted and returns a short
+// description of it. API tokens use the verify endpoint, a Global API Key is
+// checked by reading the user it belongs to.
+func verifyToken(auth cloudflareAuth) (string, error) {
+	if auth.key != "" {
+		if _, err := cloudflareDo("GET", "/user", auth, nil); err != nil {
+			return "", fmt.Errorf("global API key rejected: %v", err)
+		}
+		return "global API key for " + auth.email + " is valid", nil
+	}
+
+	body, err := cloudflareDo("GET", "/user/tokens/verify", auth, nil)
+	if err != nil {
+		return "", fmt.Errorf("API token rejected: %v", err)
+	}
+
+	var res struct {
+		Result tokenInfo `json:"result"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", fmt.Errorf("error parsing token verify response: %v", err)
+	}
+	if res.Result.Status != "active" {
+		return "", fmt.Errorf("API token is %s", res.Result.Status)
+	}
+
+	detail := "API token " + res.Result.ID + " is active"
+	if res.Result.ExpiresOn != "" {
+		detail += ", expires " + res.Result.ExpiresOn
+	}
+	return detail, nil
+}
+
+// errWriteUnconfirmed is returned by checkZoneAccess when the write probe
+// neither confirmed nor ruled out DNS edit access.
+var errWriteUnconfirmed = errors.New("DNS write access could not be confirmed")
+
+// checkZoneAccess checks that TLSA records in the zone can be read, and makes
+// a best-effort guess whether they can be written. Cloudflare offers no way
+// for a DNS-only token to read its own permissions, so writing is probed by
+// POSTing an empty record. This relies on the API checking permissions before
+// validating the body, which is observed but not documented behaviour: a
+// forbidden answer means the token lacks edit access, an invalid request
+// means it probably has it, and nothing is ever created. Any other answer is
+// reported as errWriteUnconfirmed rather than as a failure.
+func checkZoneAccess(auth cloudflareAuth, zone Zone) error {
+	if _, err := listTLSARecords(zone.ID, auth); err != nil {
+		return fmt.Errorf("cannot read DNS records of %s: %v", zone.Name, err)
+	}
+
+	status, body, err := cloudflareRequest("POST", "/zones/"+zone.ID+"/dns_records", auth, []byte("{}"))
+	if err != nil {
+		return err
+	}
+	switch {
+	case status == 401 || status == 403:
+		return fmt.Errorf("token lacks Zone:DNS:Edit on %s: %v", zone.Name, apiErrors(body))
+	case status != 400:
+		return fmt.Errorf("%w on %s, the write probe answered with status %d", errWriteUnconfirmed, zone.Name, status)
+	}
+	return nil
+}
+
+// dnssecStatus returns the DNSSEC status of a zone, e.g. active or disabled.
+func dnssecStatus(auth cloudflareAuth, zone Zone) (string, error) {
+	body, err := cloudflareDo("GET", "/zones/"+zone.ID+"/dnssec", auth, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Result struct {
+			Status string `json:"status"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", fmt.Errorf("error parsing DNSSEC response: %v", err)
+	}
+	return res.Result.Status, nil
+}
+
+// preflight verifies the credential and DNS edit access on the zone of every
+// name before any record is changed, so a missing permission does not leave
+// some ports updated and others not. It does not apply to zone files.
+func preflight(cmd *cobra.Command, auth cloudflareAuth, names []string) error {
+	if skip, _ := cmd.Flags().GetBool("skip-preflight"); skip || !usesCloudflare() {
+		return nil
+	}
+
+	if _, err := verifyToken(auth); err != nil {
+		return fmt.Errorf("preflight: %v", err)
+	}
+
+	zones, err := listZones(auth)
+	if err != nil {
+		return fmt.Errorf("preflight: %v", err)
+	}
+
+	checked := make(map[string]bool)
+	for _, name := range names {
+		zone, ok := findZone(zones, name)
+		if !ok {
+			return fmt.Errorf("preflight: no zone visible to the token for %s", name)
+		}
+		if checked[zone.ID] {
+			continue
+		}
+		if err := checkZoneAccess(auth, zone); errors.Is(err, errWriteUnconfirmed) {
+			slog.Warn("Preflight could not confirm DNS write access, continuing", "zone", zone.Name, "error", err)
+		} else if err != nil {
+			return fmt.Errorf("preflight: %v", err)
+		}
+		checked[zone.ID] = true
+		slog.Debug("Preflight passed", "zone", zone.Name)
+	}
+	return nil
+}
+
+// optionHosts returns the host of every set of options.
+func optionHosts(all []tlsaOptions) []string {
+	hosts := make([]string, len(all))
+	for i, opts := range all {
+		hosts[i] = opts.host()
+	}
+	return hosts
+}
+
+// changeNames returns the record name of every change that modifies a record.
+func changeNames(changes []recordChange) []string {
+	var names []string
+	for _, change := range changes {
+		if change.Action != actionNoop {
+			names = append(names, change.Name)
+		}
+	}
+	return names
+}
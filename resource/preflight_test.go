@@ -0,0 +1,97 @@
+package resource
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestVerifyToken(t *testing.T) {
+	newFakeCloudflare(t, "example.com")
+
+	detail, err := verifyToken(cloudflareCredentials())
+	if err != nil {
+		t.Fatalf("verifyToken() error = %v", err)
+	}
+	if !strings.Contains(detail, "active") {
+		t.Errorf("Expected active token, got %s", detail)
+	}
+
+	t.Setenv("TOKEN", "wrong-token")
+	if _, err := verifyToken(cloudflareCredentials()); err == nil {
+		t.Error("Expected error for rejected token")
+	}
+
+	t.Setenv("TOKEN", "")
+	t.Setenv("CLOUDFLARE_EMAIL", "hostmaster@example.com")
+	t.Setenv("CLOUDFLARE_API_KEY", "test-key")
+	if _, err := verifyToken(cloudflareCredentials()); err != nil {
+		t.Errorf("verifyToken() with global API key error = %v", err)
+	}
+}
+
+func TestCheckZoneAccess(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	zone := f.zones[0]
+
+	if err := checkZoneAccess(cloudflareCredentials(), zone); err != nil {
+		t.Errorf("checkZoneAccess() error = %v", err)
+	}
+	if len(f.zoneRecords("zone-1")) != 0 {
+		t.Error("The write probe must not create a record")
+	}
+
+	// An answer the heuristic does not recognise is inconclusive, not a failure
+	f.probeStatus = 500
+	if err := checkZoneAccess(cloudflareCredentials(), zone); !errors.Is(err, errWriteUnconfirmed) {
+		t.Errorf("Expected unconfirmed write access, got %v", err)
+	}
+	if err := preflight(&cobra.Command{}, cloudflareCredentials(), []string{"mail.example.com"}); err != nil {
+		t.Errorf("preflight() must continue when write access is unconfirmed, got %v", err)
+	}
+
+	f.probeStatus = 0
+	f.readOnly = true
+	err := checkZoneAccess(cloudflareCredentials(), zone)
+	if err == nil || !strings.Contains(err.Error(), "Zone:DNS:Edit") {
+		t.Errorf("Expected missing edit permission error, got %v", err)
+	}
+}
+
+func TestPreflight_StopsBeforeMutations(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.readOnly = true
+	opts := syncTestOptions(t)
+
+	cmd := &cobra.Command{}
+	addCreateFlags(cmd)
+	err := cmd.ParseFlags([]string{
+		"--url", opts.URL,
+		"--subdomain", opts.Subdomain,
+		"--cert", opts.Cert,
+		"--tcp25", "--tcp587",
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	err = ResourceCreate(cmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "preflight") {
+		t.Fatalf("Expected preflight error, got %v", err)
+	}
+	// Only the write probe may have been attempted
+	if n := f.countCalls("POST"); n != 1 {
+		t.Errorf("Expected only the write probe, got %d POST calls", n)
+	}
+
+	if err := preflight(cmd, cloudflareCredentials(), []string{"mail.example.org"}); err == nil {
+		t.Error("Expected error for a host outside the visible zones")
+	}
+
+	cmd.Flags().Bool("skip-preflight", true, "")
+	if err := preflight(cmd, cloudflareCredentials(), []string{"mail.example.org"}); err != nil {
+		t.Errorf("Expected --skip-preflight to skip the checks, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package resource
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// dnsProvider stores the TLSA records of one or more zones. Cloudflare is the
+// default; --zone-file selects a local zone file instead.
+type dnsProvider interface {
+	zones() ([]Zone, error)
+	tlsaRecords(zoneID string) ([]DNSRecord, error)
+	apply(zoneID string, batch recordBatch) (*batchResult, error)
+}
+
+// cloudflareProvider reads and writes records through the Cloudflare API.
+type cloudflareProvider struct {
+	auth cloudflareAuth
+}
+
+func (p cloudflareProvider) zones() ([]Zone, error) {
+	return listZones(p.auth)
+}
+
+func (p cloudflareProvider) tlsaRecords(zoneID string) ([]DNSRecord, error) {
+	return listTLSARecords(zoneID, p.auth)
+}
+
+func (p cloudflareProvider) apply(zoneID string, batch recordBatch) (*batchResult, error) {
+	return applyBatch(zoneID, p.auth, batch)
+}
+
+// activeProvider is set by ConfigureProvider when --zone-file is given.
+// Otherwise records are kept in Cloudflare.
+var activeProvider dnsProvider
+
+// providerFor returns the provider for a run with the given credentials.
+func providerFor(auth cloudflareAuth) dnsProvider {
+	if activeProvider != nil {
+		return activeProvider
+	}
+	return cloudflareProvider{auth: auth}
+}
+
+// usesCloudflare reports whether records are kept in Cloudflare, which is
+// when the token checks of preflight and doctor apply.
+func usesCloudflare() bool {
+	return activeProvider == nil
+}
+
+// ConfigureProvider selects the file provider for commands given --zone-file.
+func ConfigureProvider(cmd *cobra.Command, args []string) error {
+	activeProvider = nil
+	f := cmd.Flags().Lookup("zone-file")
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+	origin, _ := cmd.Flags().GetString("zone-origin")
+	activeProvider = newFileProvider(f.Value.String(), origin)
+	return nil
+}
@@ -0,0 +1,37 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestConfigureProvider(t *testing.T) {
+	t.Cleanup(func() { activeProvider = nil })
+
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("zone-file", "", "")
+		cmd.Flags().String("zone-origin", "", "")
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+		return cmd
+	}
+
+	if err := ConfigureProvider(newCmd("--zone-file", "example.com.zone", "--zone-origin", "Example.com."), nil); err != nil {
+		t.Fatalf("ConfigureProvider() error = %v", err)
+	}
+	file, ok := activeProvider.(*fileProvider)
+	if !ok || file.path != "example.com.zone" || file.origin != "example.com" || usesCloudflare() {
+		t.Errorf("Expected the file provider, got %+v", activeProvider)
+	}
+
+	// Commands without --zone-file use Cloudflare
+	if err := ConfigureProvider(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("ConfigureProvider() error = %v", err)
+	}
+	if _, ok := providerFor(cloudflareAuth{token: "test"}).(cloudflareProvider); !ok || !usesCloudflare() {
+		t.Errorf("Expected the Cloudflare provider, got %+v", activeProvider)
+	}
+}
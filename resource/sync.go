@@ -0,0 +1,318 @@
+package resource
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func ResourceSync(cmd *cobra.Command, args []string) error {
+	all, err := loadTLSAOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	prune, err := cmd.Flags().GetBool("prune")
+	if err != nil {
+		return err
+	}
+
+	auth := cloudflareCredentials()
+
+	if stop, err := handleDryRun(cmd, "sync", func() ([]recordChange, error) {
+		return planSync(all, auth, prune)
+	}); stop {
+		return err
+	}
+
+	changes, err := planSync(all, auth, prune)
+	if err != nil {
+		return err
+	}
+
+	if err := preflight(cmd, auth, changeNames(changes)); err != nil {
+		return err
+	}
+
+	return applyChanges(changes, auth)
+}
+
+// recordChange is one step needed to converge the provider on the desired
+// TLSA records.
+type recordChange struct {
+	Action string       `json:"action"`
+	ZoneID string       `json:"zone_id"`
+	Name   string       `json:"name"`
+	Old    *DNSRecord   `json:"old,omitempty"`
+	New    *JSONRequest `json:"new,omitempty"`
+	Note   string       `json:"note,omitempty"`
+}
+
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+	actionNoop   = "noop"
+)
+
+// planSync compares the records described by the options with the TLSA
+// records at the provider. Only records carrying managedMarker are updated or
+// deleted. Managed records that are no longer desired are deleted when they
+// belong to one of the hosts being synced, or anywhere in the zone with prune.
+func planSync(all []tlsaOptions, auth cloudflareAuth, prune bool) ([]recordChange, error) {
+	for _, opts := range all {
+		if _, _, err := opts.hashes(); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot, err := newProviderSnapshot(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// updated holds the desired records with the comment written when they
+	// replace an existing record
+	type zoneState struct {
+		desired map[string][]JSONRequest
+		updated map[string][]JSONRequest
+		hosts   map[string]bool
+	}
+	states := make(map[string]*zoneState)
+	var zoneIDs []string
+
+	for _, opts := range all {
+		zone, err := snapshot.zoneFor(opts.host())
+		if err != nil {
+			return nil, err
+		}
+
+		state := states[zone.ID]
+		if state == nil {
+			state = &zoneState{desired: make(map[string][]JSONRequest), updated: make(map[string][]JSONRequest), hosts: make(map[string]bool)}
+			states[zone.ID] = state
+			zoneIDs = append(zoneIDs, zone.ID)
+		}
+		state.hosts[opts.host()] = true
+
+		updated, err := opts.records("Updated")
+		if err != nil {
+			return nil, err
+		}
+		created, err := opts.records("Created")
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range created {
+			name := strings.ToLower(record.Name + "." + opts.URL)
+			state.desired[name] = append(state.desired[name], record)
+			state.updated[name] = append(state.updated[name], updated[i])
+		}
+	}
+
+	var changes []recordChange
+	for _, zoneID := range zoneIDs {
+		state := states[zoneID]
+
+		actual, err := snapshot.tlsaRecords(zoneID)
+		if err != nil {
+			return nil, err
+		}
+
+		byName := make(map[string][]DNSRecord)
+		for _, record := range actual {
+			name := strings.ToLower(record.Name)
+			byName[name] = append(byName[name], record)
+		}
+
+		names := make([]string, 0, len(state.desired))
+		for name := range state.desired {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			changes = append(changes, reconcileName(zoneID, name, state.desired[name], state.updated[name], byName[name])...)
+		}
+
+		for _, record := range actual {
+			name := strings.ToLower(record.Name)
+			if _, ok := state.desired[name]; ok || !isManaged(record) {
+				continue
+			}
+			if prune || state.hosts[tlsaHost(name)] {
+				old := record
+				changes = append(changes, recordChange{Action: actionDelete, ZoneID: zoneID, Name: name, Old: &old})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// reconcileName decides the changes for the records at a single owner name.
+// updated holds the same records as desired, with the comment for replacing
+// an existing record.
+func reconcileName(zoneID, name string, desired, updated []JSONRequest, actual []DNSRecord) []recordChange {
+	var changes []recordChange
+	used := make([]bool, len(actual))
+	var pending []int
+
+	// Records that already match exactly need no change
+	for j, want := range desired {
+		matched := false
+		for i, have := range actual {
+			if !used[i] && sameTLSAData(want, have) && (sameRecordSettings(want, have) || !isManaged(have)) {
+				used[i] = true
+				matched = true
+				old := have
+				changes = append(changes, recordChange{Action: actionNoop, ZoneID: zoneID, Name: name, Old: &old})
+				break
+			}
+		}
+		if !matched {
+			pending = append(pending, j)
+		}
+	}
+
+	// Remaining desired records replace a managed record of the same usage,
+	// otherwise they are created
+	for _, j := range pending {
+		want := desired[j]
+		replaced := false
+		for i, have := range actual {
+			if used[i] || !isManaged(have) || have.Data.Usage != want.Data.Usage {
+				continue
+			}
+			used[i] = true
+			replaced = true
+			old := have
+			next := updated[j]
+			changes = append(changes, recordChange{Action: actionUpdate, ZoneID: zoneID, Name: name, Old: &old, New: &next})
+			break
+		}
+		if !replaced {
+			next := want
+			changes = append(changes, recordChange{Action: actionCreate, ZoneID: zoneID, Name: name, New: &next})
+		}
+	}
+
+	for i, have := range actual {
+		if used[i] {
+			continue
+		}
+		if !isManaged(have) {
+			slog.Warn("Leaving unmanaged TLSA record untouched", "name", name, "data", formatTLSAData(have.Data.Usage, have.Data.Selector, have.Data.MatchingType, have.Data.Certificate))
+			continue
+		}
+		old := have
+		changes = append(changes, recordChange{Action: actionDelete, ZoneID: zoneID, Name: name, Old: &old})
+	}
+
+	return changes
+}
+
+func sameTLSAData(want JSONRequest, have DNSRecord) bool {
+	return want.Data.Usage == have.Data.Usage &&
+		want.Data.Selector == have.Data.Selector &&
+		want.Data.Matchingtype == have.Data.MatchingType &&
+		strings.EqualFold(want.Data.Certificate, have.Data.Certificate)
+}
+
+// sameRecordSettings reports whether the TTL and tags of a record match.
+func sameRecordSettings(want JSONRequest, have DNSRecord) bool {
+	if want.Ttl != have.TTL || len(want.Tags) != len(have.Tags) {
+		return false
+	}
+	wantTags := slices.Sorted(slices.Values(want.Tags))
+	haveTags := slices.Sorted(slices.Values(have.Tags))
+	return slices.Equal(wantTags, haveTags)
+}
+
+// tlsaHost strips the _port._proto labels from a TLSA owner name.
+func tlsaHost(name string) string {
+	labels := strings.SplitN(name, ".", 3)
+	if len(labels) == 3 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_") {
+		return labels[2]
+	}
+	return name
+}
+
+func formatTLSAData(usage, selector, matchingType int, certificate string) string {
+	return fmt.Sprintf("%d %d %d %s", usage, selector, matchingType, certificate)
+}
+
+// applyChanges makes the planned changes, sending the changes to each zone
+// as one atomic batch. A failed zone does not stop the others, and the first
+// error is returned.
+func applyChanges(changes []recordChange, auth cloudflareAuth) error {
+	provider := providerFor(auth)
+	var errs []error
+	counts := make(map[string]int)
+
+	var zoneIDs []string
+	byZone := make(map[string][]int)
+	for i, change := range changes {
+		if change.Action == actionNoop {
+			counts[actionNoop]++
+			continue
+		}
+		if _, ok := byZone[change.ZoneID]; !ok {
+			zoneIDs = append(zoneIDs, change.ZoneID)
+		}
+		byZone[change.ZoneID] = append(byZone[change.ZoneID], i)
+	}
+
+	for _, zoneID := range zoneIDs {
+		var batch recordBatch
+		var deletes, puts, posts []int
+		for _, i := range byZone[zoneID] {
+			change := changes[i]
+			switch change.Action {
+			case actionCreate:
+				batch.Posts = append(batch.Posts, *change.New)
+				posts = append(posts, i)
+			case actionUpdate:
+				batch.Puts = append(batch.Puts, batchPut{ID: change.Old.ID, JSONRequest: *change.New})
+				puts = append(puts, i)
+			case actionDelete:
+				batch.Deletes = append(batch.Deletes, batchDelete{ID: change.Old.ID})
+				deletes = append(deletes, i)
+			}
+		}
+
+		result, err := provider.apply(zoneID, batch)
+		applied := func(indexes []int, n int) {
+			for j, i := range indexes {
+				change := changes[i]
+				if j >= n {
+					errs = append(errs, fmt.Errorf("error applying %s of %s: %w", change.Action, change.Name, err))
+					continue
+				}
+				slog.Info("Applied change", "action", change.Action, "name", change.Name)
+				if change.Action == actionCreate || change.Action == actionUpdate {
+					markSynced(change.Name)
+				}
+				counts[change.Action]++
+			}
+		}
+		applied(deletes, len(result.Deletes))
+		applied(puts, len(result.Puts))
+		applied(posts, len(result.Posts))
+	}
+
+	slog.Info("Changes applied",
+		"created", counts[actionCreate], "updated", counts[actionUpdate], "deleted", counts[actionDelete], "unchanged", counts[actionNoop])
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			slog.Error("Change failed", "error", err)
+		}
+		return errs[0]
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package resource
+
+import (
+	"testing"
+)
+
+func syncTestOptions(t *testing.T) tlsaOptions {
+	t.Helper()
+
+	return tlsaOptions{
+		URL:          "example.com",
+		Subdomain:    "mail",
+		Cert:         generateTestCertForReq(t),
+		Services:     []tlsaService{{Port: "25", Protocol: "tcp"}, {Port: "587", Protocol: "tcp"}},
+		DaneEE:       true,
+		Selector:     -1,
+		MatchingType: 1,
+	}
+}
+
+func countActions(changes []recordChange) map[string]int {
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Action]++
+	}
+	return counts
+}
+
+func TestSync_CreatesThenIsIdempotent(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	auth := cloudflareCredentials()
+
+	changes, err := planSync([]tlsaOptions{opts}, auth, false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionCreate] != 2 {
+		t.Fatalf("Expected 2 creates, got %v", counts)
+	}
+	if err := applyChanges(changes, auth); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	if len(f.zoneRecords("zone-1")) != 2 {
+		t.Fatalf("Expected 2 records after sync, got %d", len(f.zoneRecords("zone-1")))
+	}
+
+	// A second run must not change anything
+	changes, err = planSync([]tlsaOptions{opts}, auth, false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionNoop] != 2 || len(counts) != 1 {
+		t.Errorf("Expected only 2 noops on second run, got %v", counts)
+	}
+}
+
+func TestSync_UpdatesAndDeletesManagedOnly(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = opts.Services[:1]
+	auth := cloudflareCredentials()
+
+	stale := f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "00ff", "Created by GoTLSAFlare - 2024-01-01 00:00:00")
+	removed := f.addRecord("zone-1", "_587._tcp.mail.example.com", 3, 1, 1, "00ff", "Created by GoTLSAFlare - 2024-01-01 00:00:00")
+	manual := f.addRecord("zone-1", "_465._tcp.mail.example.com", 3, 1, 1, "00ff", "hand made")
+	otherHost := f.addRecord("zone-1", "_25._tcp.mx2.example.com", 3, 1, 1, "00ff", "Created by GoTLSAFlare - 2024-01-01 00:00:00")
+
+	changes, err := planSync([]tlsaOptions{opts}, auth, false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+
+	byID := make(map[string]string)
+	for _, change := range changes {
+		if change.Old != nil {
+			byID[change.Old.ID] = change.Action
+		}
+	}
+
+	if byID[stale.ID] != actionUpdate {
+		t.Errorf("Expected stale managed record to be updated, got %q", byID[stale.ID])
+	}
+	if byID[removed.ID] != actionDelete {
+		t.Errorf("Expected managed record for removed port to be deleted, got %q", byID[removed.ID])
+	}
+	if _, ok := byID[manual.ID]; ok {
+		t.Errorf("Unmanaged record must not be touched, got %q", byID[manual.ID])
+	}
+	if _, ok := byID[otherHost.ID]; ok {
+		t.Errorf("Managed record of another host must not be touched without prune, got %q", byID[otherHost.ID])
+	}
+
+	if err := applyChanges(changes, auth); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	if len(f.zoneRecords("zone-1")) != 3 {
+		t.Errorf("Expected 3 records after sync, got %d", len(f.zoneRecords("zone-1")))
+	}
+
+	// With prune the other host's managed record goes as well
+	changes, err = planSync([]tlsaOptions{opts}, auth, true)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionDelete] != 1 {
+		t.Errorf("Expected 1 delete with prune, got %v", counts)
+	}
+}
+
+func TestSync_UnmanagedExactMatchSatisfiesDesired(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = opts.Services[:1]
+
+	eeHash, _, err := opts.hashes()
+	if err != nil {
+		t.Fatalf("hashes() error = %v", err)
+	}
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, eeHash, "hand made")
+
+	changes, err := planSync([]tlsaOptions{opts}, cloudflareCredentials(), false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionNoop] != 1 || len(counts) != 1 {
+		t.Errorf("Expected a single noop, got %v", counts)
+	}
+}
+
+func TestSync_UpdatesTTLAndTags(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	auth := cloudflareCredentials()
+
+	changes, err := planSync([]tlsaOptions{opts}, auth, false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if err := applyChanges(changes, auth); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+
+	opts.TTL = 300
+	opts.Tags = []string{"team:mail"}
+	opts.Comment = "CHG-1234 {{.Action}}"
+	changes, err = planSync([]tlsaOptions{opts}, auth, false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionUpdate] != 2 || len(counts) != 1 {
+		t.Fatalf("Expected 2 updates for new TTL and tags, got %v", counts)
+	}
+	if err := applyChanges(changes, auth); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+
+	for _, record := range f.zoneRecords("zone-1") {
+		if record.TTL != 300 || len(record.Tags) != 1 || record.Comment != "CHG-1234 Updated "+managedMarker {
+			t.Errorf("Unexpected record after update: ttl=%d tags=%v comment=%q", record.TTL, record.Tags, record.Comment)
+		}
+	}
+}
+
+func TestSync_NoMatchingZone(t *testing.T) {
+	newFakeCloudflare(t, "example.org")
+	opts := syncTestOptions(t)
+
+	if _, err := planSync([]tlsaOptions{opts}, cloudflareCredentials(), false); err == nil {
+		t.Error("Expected error when no zone matches")
+	}
+}
+
+func TestTLSAHost(t *testing.T) {
+	if got := tlsaHost("_25._tcp.mail.example.com"); got != "mail.example.com" {
+		t.Errorf("tlsaHost() = %s, want mail.example.com", got)
+	}
+	if got := tlsaHost("mail.example.com"); got != "mail.example.com" {
+		t.Errorf("tlsaHost() = %s, want mail.example.com", got)
+	}
+}
@@ -5,13 +5,14 @@ import (
 )
 
 type JSONRequest struct {
-	Type     string `json:"type"`
-	Name     string `json:"name"`
-	Data     Data   `json:"data"`
-	Ttl      int    `json:"ttl"`
-	Priority int    `json:"priority"`
-	Proxied  bool   `json:"proxied"`
-	Comment  string `json:"comment"`
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Data     Data     `json:"data"`
+	Ttl      int      `json:"ttl"`
+	Priority int      `json:"priority"`
+	Proxied  bool     `json:"proxied"`
+	Comment  string   `json:"comment"`
+	Tags     []string `json:"tags,omitempty"`
 }
 
 type Data struct {
@@ -85,6 +86,58 @@ type Res struct {
 	Messages []interface{} `json:"messages"`
 }
 
+type Zone = struct {
+	ID                  string      `json:"id"`
+	Name                string      `json:"name"`
+	Status              string      `json:"status"`
+	Paused              bool        `json:"paused"`
+	Type                string      `json:"type"`
+	DevelopmentMode     int         `json:"development_mode"`
+	NameServers         []string    `json:"name_servers"`
+	OriginalNameServers []string    `json:"original_name_servers"`
+	OriginalRegistrar   interface{} `json:"original_registrar"`
+	OriginalDnshost     interface{} `json:"original_dnshost"`
+	ModifiedOn          time.Time   `json:"modified_on"`
+	CreatedOn           time.Time   `json:"created_on"`
+	ActivatedOn         time.Time   `json:"activated_on"`
+	Meta                struct {
+		Step                    int  `json:"step"`
+		CustomCertificateQuota  int  `json:"custom_certificate_quota"`
+		PageRuleQuota           int  `json:"page_rule_quota"`
+		PhishingDetected        bool `json:"phishing_detected"`
+		MultipleRailgunsAllowed bool `json:"multiple_railguns_allowed"`
+	} `json:"meta"`
+	Owner struct {
+		ID    interface{} `json:"id"`
+		Type  string      `json:"type"`
+		Email interface{} `json:"email"`
+	} `json:"owner"`
+	Account struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"account"`
+	Tenant struct {
+		ID   interface{} `json:"id"`
+		Name interface{} `json:"name"`
+	} `json:"tenant"`
+	TenantUnit struct {
+		ID interface{} `json:"id"`
+	} `json:"tenant_unit"`
+	Permissions []string `json:"permissions"`
+	Plan        struct {
+		ID                string `json:"id"`
+		Name              string `json:"name"`
+		Price             int    `json:"price"`
+		Currency          string `json:"currency"`
+		Frequency         string `json:"frequency"`
+		IsSubscribed      bool   `json:"is_subscribed"`
+		CanSubscribe      bool   `json:"can_subscribe"`
+		LegacyID          string `json:"legacy_id"`
+		LegacyDiscount    bool   `json:"legacy_discount"`
+		ExternallyManaged bool   `json:"externally_managed"`
+	} `json:"plan"`
+}
+
 type RecordsRes struct {
 	Result []struct {
 		ID        string `json:"id"`
@@ -109,10 +162,10 @@ type RecordsRes struct {
 			ManagedByArgoTunnel bool   `json:"managed_by_argo_tunnel"`
 			Source              string `json:"source"`
 		} `json:"meta"`
-		Comment    string        `json:"comment"`
-		Tags       []interface{} `json:"tags"`
-		CreatedOn  time.Time     `json:"created_on"`
-		ModifiedOn time.Time     `json:"modified_on"`
+		Comment    string    `json:"comment"`
+		Tags       []string  `json:"tags"`
+		CreatedOn  time.Time `json:"created_on"`
+		ModifiedOn time.Time `json:"modified_on"`
 	} `json:"result"`
 	Success    bool          `json:"success"`
 	Errors     []interface{} `json:"errors"`
@@ -149,8 +202,8 @@ type DNSRecord = struct {
 		ManagedByArgoTunnel bool   `json:"managed_by_argo_tunnel"`
 		Source              string `json:"source"`
 	} `json:"meta"`
-	Comment    string        `json:"comment"`
-	Tags       []interface{} `json:"tags"`
-	CreatedOn  time.Time     `json:"created_on"`
-	ModifiedOn time.Time     `json:"modified_on"`
+	Comment    string    `json:"comment"`
+	Tags       []string  `json:"tags"`
+	CreatedOn  time.Time `json:"created_on"`
+	ModifiedOn time.Time `json:"modified_on"`
 }
@@ -1,15 +1,14 @@
 package resource
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/miekg/dns"
@@ -17,381 +16,444 @@ import (
 )
 
 func ResourceUpdate(cmd *cobra.Command, args []string) error {
-	url, err := cmd.Flags().GetString("url")
-	if err != nil {
-		return err
-	}
-	subdomain, err := cmd.Flags().GetString("subdomain")
-	if err != nil {
-		return err
-	}
-	cert, err := cmd.Flags().GetString("cert")
-	if err != nil {
-		return err
-	}
-	tcp25, err := cmd.Flags().GetBool("tcp25")
-	if err != nil {
-		return err
-	}
-	tcp465, err := cmd.Flags().GetBool("tcp465")
-	if err != nil {
-		return err
-	}
-	tcp587, err := cmd.Flags().GetBool("tcp587")
+	all, err := loadTLSAOptions(cmd)
 	if err != nil {
 		return err
 	}
 
-	daneEE, err := cmd.Flags().GetBool("dane-ee")
+	rollover, err := cmd.Flags().GetBool("rollover")
 	if err != nil {
 		return err
 	}
 
-	noDaneEE, err := cmd.Flags().GetBool("no-dane-ee")
+	rollback, err := cmd.Flags().GetBool("rollback-on-error")
 	if err != nil {
 		return err
 	}
 
-	daneTa, err := cmd.Flags().GetBool("dane-ta")
+	concurrency, err := cmd.Flags().GetInt("concurrency")
 	if err != nil {
 		return err
 	}
-
-	tcpPort, err := cmd.Flags().GetInt("tcp-port")
-	if err != nil {
-		return err
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
 	}
 
-	rollover, err := cmd.Flags().GetBool("rollover")
-	if err != nil {
+	if stop, err := handleDryRun(cmd, "update", func() ([]recordChange, error) {
+		return planUpdate(all, cloudflareCredentials(), rollover)
+	}); stop {
 		return err
 	}
 
-	selector, err := cmd.Flags().GetInt("selector")
+	output, err := outputFormat(cmd)
 	if err != nil {
 		return err
 	}
 
-	matchingType, err := cmd.Flags().GetInt("matching-type")
-	if err != nil {
+	if err := configureNotifiers(cmd); err != nil {
 		return err
 	}
 
-	// Handle the case where both --dane-ee and --no-dane-ee are specified
-	if noDaneEE {
-		daneEE = false
+	if err := preflight(cmd, cloudflareCredentials(), optionHosts(all)); err != nil {
+		return writeResults(os.Stdout, output, "update", nil, err)
 	}
 
-	// Ensure at least one of DANE-EE or DANE-TA is enabled
-	if !daneEE && !daneTa {
-		fmt.Println("Error: At least one of DANE-EE or DANE-TA must be enabled")
-		os.Exit(1)
-	}
-
-	// Validate matching type
-	if matchingType != 1 && matchingType != 2 {
-		fmt.Println("Error: Matching type must be either 1 (SHA2-256) or 2 (SHA2-512)")
-		os.Exit(1)
+	if metricsListen, _ := cmd.Flags().GetString("metrics-listen"); metricsListen != "" {
+		if _, err := serveMetrics(metricsListen); err != nil {
+			return err
+		}
+		for _, opts := range all {
+			updateCertExpiry(opts.Cert)
+		}
 	}
 
-	var updateErrors []error
+	results, err := runUpdateAll(context.Background(), all, rollover, rollback, concurrency)
+	return writeResults(os.Stdout, output, "update", results, err)
+}
 
-	handlePortUpdate := func(port string) {
-		prefix := "_" + port + "._tcp."
-		domain := subdomain + "." + url
+// updateJob is one TLSA record to replace.
+type updateJob struct {
+	svc    tlsaService
+	host   string
+	label  string
+	record JSONRequest
+	roll   bool
+}
 
-		// Use appropriate selectors for each usage type if not explicitly specified
-		eeSel := selector
-		taSel := selector
+func (j updateJob) name() string {
+	return j.svc.prefix() + j.host
+}
 
-		// If selector is not explicitly set (-1), use defaults
-		if selector == -1 {
-			eeSel = 1 // Default to SPKI(1) for DANE-EE
-			taSel = 0 // Default to Cert(0) for DANE-TA
-		}
+// updateJobs lists the records of the options, DANE-EE before DANE-TA for
+// each service.
+func updateJobs(opts tlsaOptions, rollover bool) ([]updateJob, error) {
+	eeHash, caHash, err := opts.hashes()
+	if err != nil {
+		return nil, err
+	}
+	// Use appropriate selectors for each usage type if not explicitly specified
+	eeSel, taSel := opts.selectors()
 
-		if daneEE {
-			eeReq := genCloudflareReq(cert, port, "tcp", subdomain, "Updated", 3, eeSel, matchingType)
-			if rollover {
-				err := performRollover(prefix, domain, eeReq)
-				if err != nil {
-					updateErrors = append(updateErrors, fmt.Errorf("error performing DANE-EE rollover for port %s: %w", port, err))
-				}
-			} else {
-				err := putToCloudflare(prefix, domain, eeReq)
-				if err != nil {
-					updateErrors = append(updateErrors, fmt.Errorf("error updating DANE-EE for port %s: %w", port, err))
-				}
-			}
+	var jobs []updateJob
+	for _, svc := range opts.Services {
+		if opts.DaneEE {
+			jobs = append(jobs, updateJob{svc: svc, host: opts.host(), label: "DANE-EE", record: opts.record(svc, "Updated", 3, eeSel, eeHash), roll: rollover})
 		}
-
-		if daneTa {
-			taReq := genCloudflareReq(cert, port, "tcp", subdomain, "Updated", 2, taSel, matchingType)
-			if rollover && !daneEE {
-				// Only use rollover for DANE-TA if DANE-EE is not enabled
-				err := performRollover(prefix, domain, taReq)
-				if err != nil {
-					updateErrors = append(updateErrors, fmt.Errorf("error performing DANE-TA rollover for port %s: %w", port, err))
-				}
-			} else {
-				err := putToCloudflare(prefix, domain, taReq)
-				if err != nil {
-					updateErrors = append(updateErrors, fmt.Errorf("error updating DANE-TA for port %s: %w", port, err))
-				}
-			}
+		if opts.DaneTA {
+			// Only use rollover for DANE-TA if DANE-EE is not enabled
+			jobs = append(jobs, updateJob{svc: svc, host: opts.host(), label: "DANE-TA", record: opts.record(svc, "Updated", 2, taSel, caHash), roll: rollover && !opts.DaneEE})
 		}
 	}
+	return jobs, nil
+}
 
-	// Collect all ports to process
-	var ports []string
-	if tcpPort != 0 {
-		ports = append(ports, strconv.Itoa(tcpPort))
-	}
-	if tcp25 {
-		ports = append(ports, "25")
-	}
-	if tcp465 {
-		ports = append(ports, "465")
+// runUpdateAll updates the records of every set of options. The changes to
+// each zone are sent as one atomic batch, at most concurrency zones at a
+// time. With rollover the new records are published next to the old ones,
+// and after a single shared wait the old records whose replacement has
+// propagated are deleted in a second batch per zone. It returns the first
+// error after attempting every record. Zones and records are listed once for
+// the whole run. With rollback, any failure reverses every change the run
+// made, so all records are left as they were. In a zone file the old records
+// of a rollover are kept, for a later sync to remove. If ctx is done during
+// the wait, the run stops early and keeps the old records.
+func runUpdateAll(ctx context.Context, all []tlsaOptions, rollover, rollback bool, concurrency int) ([]recordResult, error) {
+	snapshot, err := newProviderSnapshot(cloudflareCredentials())
+	if err != nil {
+		return nil, err
 	}
-	if tcp587 {
-		ports = append(ports, "587")
+	if rollback {
+		snapshot.journal = &journal{}
 	}
+	return updateRecords(ctx, snapshot, all, rollover, rollback, concurrency)
+}
 
-	// Validate that at least one port is specified
-	if len(ports) == 0 {
-		return fmt.Errorf("no ports specified. Please specify at least one port using --tcp-port, --tcp25, --tcp465, or --tcp587")
+// updateRecords is runUpdateAll with zones and records read from snapshot.
+// Rollback needs the snapshot to keep a journal.
+func updateRecords(ctx context.Context, snapshot *providerSnapshot, all []tlsaOptions, rollover, rollback bool, concurrency int) ([]recordResult, error) {
+	var jobs []updateJob
+	for _, opts := range all {
+		optsJobs, err := updateJobs(opts, rollover)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, optsJobs...)
+	}
+
+	// Workers only write the slots of their own jobs, so the slices need no
+	// locking and keep the order of jobs
+	run := &updateRun{
+		snapshot:     snapshot,
+		jobs:         jobs,
+		results:      make([]recordResult, len(jobs)),
+		updateErrors: make([]error, len(jobs)),
+		old:          make([]*DNSRecord, len(jobs)),
+		newIDs:       make([]string, len(jobs)),
+		byZone:       make(map[string][]int),
+	}
+
+	// Find the record each job replaces, grouping the jobs by zone
+	for i, job := range jobs {
+		zone, old, err := existingRecord(snapshot, job.name(), job.host, job.record.Data.Usage)
+		if err == nil && old == nil {
+			err = fmt.Errorf("could not find existing TLSA record with usage %d for %s", job.record.Data.Usage, job.name())
+		}
+		if err != nil {
+			run.fail(i, "", err)
+			continue
+		}
+		run.old[i] = old
+		if _, ok := run.byZone[zone.ID]; !ok {
+			run.zones = append(run.zones, zone)
+		}
+		run.byZone[zone.ID] = append(run.byZone[zone.ID], i)
 	}
 
-	// Process all ports
-	for _, port := range ports {
-		handlePortUpdate(port)
+	runConcurrently(len(run.zones), concurrency, func(z int) { run.publish(run.zones[z]) })
+	if rollback && run.failed() {
+		// Undo the published records at once rather than after the wait
+		return run.rollback()
 	}
 
-	// Return the first error if any occurred during updates
-	if len(updateErrors) > 0 {
-		for _, err := range updateErrors {
-			fmt.Println(err)
+	var wait time.Duration
+	var waiting []int
+	for i, id := range run.newIDs {
+		if id != "" {
+			wait = max(wait, rolloverWait(run.old[i].TTL, jobs[i].record.Ttl))
+			waiting = append(waiting, i)
 		}
-		return updateErrors[0]
 	}
-
-	return nil
-}
-
-func putToCloudflare(portandprotocol string, nameanddomain string, putBody string) error {
-	url := "https://api.cloudflare.com/client/v4/zones"
-	var bearer = "Bearer " + os.Getenv("TOKEN")
-
-	// Extract usage value from putBody
-	var jsonReq JSONRequest
-	if err := json.Unmarshal([]byte(putBody), &jsonReq); err != nil {
-		log.Printf("Error parsing request body: %v\n", err)
-		return fmt.Errorf("error parsing request body: %v", err)
+	if _, ok := snapshot.provider.(*fileProvider); ok && len(waiting) > 0 {
+		// A zone file only takes effect once it is committed and deployed,
+		// which this run cannot see. Keep both records, a later sync
+		// removes the old ones.
+		slog.Info("Zone file holds old and new TLSA records, run sync after the zone is deployed and 2 TTL periods have passed to remove the old ones", "records", len(waiting), "wait", wait)
+		waiting = nil
 	}
-	usage := jsonReq.Data.Usage
+	if len(waiting) > 0 {
+		// Wait for 2 rounds of TTL as per DANE certificate rollover best practices
+		slog.Info("Waiting 2 TTL periods to ensure DNS propagation", "records", len(waiting), "wait", wait)
+		phaseStart := time.Now()
+		err := rolloverSleep(ctx, wait)
+		observePhase("propagation_wait", phaseStart)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Println(err)
-		return fmt.Errorf("error creating request: %v", err)
+		run.propagated = make([]bool, len(jobs))
+		if err != nil {
+			// Stopped during the wait, keep the old records as when
+			// propagation fails
+			for _, i := range waiting {
+				slog.Warn("Rollover interrupted, preserving old TLSA record. Both old and new records will remain.", "name", jobs[i].name(), "error", err)
+				run.fail(i, run.newIDs[i], fmt.Errorf("rollover interrupted: %v - old record preserved for safety", err))
+			}
+		} else {
+			runConcurrently(len(waiting), concurrency, func(w int) { run.checkPropagation(waiting[w]) })
+		}
+		runConcurrently(len(run.zones), concurrency, func(z int) { run.deleteOld(run.zones[z]) })
 	}
-
-	req.Header.Add("Authorization", bearer)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Println("Error on response.\n[ERROR] -", err)
-		return fmt.Errorf("error on response: %v", err)
+	if rollback && run.failed() {
+		return run.rollback()
 	}
-	defer resp.Body.Close()
+	return run.results, run.firstError()
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("Error while reading the response bytes:", err)
-		return fmt.Errorf("error while reading the response bytes: %v", err)
-	}
+// updateRun is the state of one runUpdateAll, indexed by job.
+type updateRun struct {
+	snapshot     *providerSnapshot
+	jobs         []updateJob
+	results      []recordResult
+	updateErrors []error
+	old          []*DNSRecord
+	newIDs       []string
+	propagated   []bool
+	zones        []Zone
+	byZone       map[string][]int
+}
 
-	var res Res
-	if err := json.Unmarshal(body, &res); err != nil {
-		log.Println(err)
-		return fmt.Errorf("error parsing JSON response: %v", err)
-	}
+// failed reports whether any record failed.
+func (r *updateRun) failed() bool {
+	return slices.ContainsFunc(r.updateErrors, func(err error) bool { return err != nil })
+}
 
-	zoneID := ""
-	for _, zone := range res.Result {
-		if strings.HasSuffix(nameanddomain, zone.Name) {
-			zoneID = zone.ID
+// firstError logs every failure and returns the first.
+func (r *updateRun) firstError() error {
+	var firstErr error
+	for _, err := range r.updateErrors {
+		if err == nil {
+			continue
+		}
+		slog.Error("TLSA update failed", "error", err)
+		if firstErr == nil {
+			firstErr = err
 		}
 	}
+	return firstErr
+}
 
-	if zoneID == "" {
-		log.Println("No matching zones found")
-		return fmt.Errorf("no matching zones found")
-	}
-
-	searchurl := "https://api.cloudflare.com/client/v4/zones/" + zoneID + "/dns_records"
-
-	req2, err2 := http.NewRequest("GET", searchurl, nil)
-	if err2 != nil {
-		log.Println(err2)
-		return fmt.Errorf("error creating search request: %v", err2)
-	}
-
-	req2.Header.Add("Authorization", bearer)
+// rollback reverses the changes of the run after a failure. The failure is
+// still returned, with the rollback error if records could not be restored.
+func (r *updateRun) rollback() ([]recordResult, error) {
+	err := r.firstError()
+	slog.Warn("Rolling back TLSA records changed by this run", "error", err)
+	restored, rollbackErr := rollback(r.snapshot)
+	results := append(r.results, restored...)
+	if rollbackErr != nil {
+		return results, fmt.Errorf("%w; rollback failed: %v", err, rollbackErr)
+	}
+	return results, fmt.Errorf("%w; rolled back %d records", err, len(restored))
+}
 
-	resp2, err2 := client.Do(req2)
-	if err2 != nil {
-		log.Println("Error on response.\n[ERROR] -", err2)
-		return fmt.Errorf("error on search response: %v", err2)
+func (r *updateRun) fail(i int, id string, err error) {
+	job := r.jobs[i]
+	if job.roll {
+		r.results[i] = newRecordResult(job.name(), actionRollover, id, err)
+		if !errors.Is(err, errPropagation) {
+			notify(eventFailed, job.name(), id, "Error performing "+job.label+" rollover", err)
+		}
+		r.updateErrors[i] = fmt.Errorf("error performing %s rollover for port %s: %w", job.label, job.svc.Port, err)
+		return
 	}
-	defer resp2.Body.Close()
+	r.results[i] = newRecordResult(job.name(), actionUpdate, id, err)
+	notify(eventFailed, job.name(), id, "Error updating "+job.label+" record", err)
+	r.updateErrors[i] = fmt.Errorf("error updating %s for port %s: %w", job.label, job.svc.Port, err)
+}
 
-	body2, err2 := io.ReadAll(resp2.Body)
-	if err2 != nil {
-		log.Println("Error while reading the response bytes:", err2)
-		return fmt.Errorf("error while reading the search response bytes: %v", err2)
+// publish replaces the records of a zone in place, and adds the new records
+// of rollovers next to the old ones.
+func (r *updateRun) publish(zone Zone) {
+	var batch recordBatch
+	var puts, posts []int
+	for _, i := range r.byZone[zone.ID] {
+		if r.jobs[i].roll {
+			batch.Posts = append(batch.Posts, r.jobs[i].record)
+			posts = append(posts, i)
+		} else {
+			batch.Puts = append(batch.Puts, batchPut{ID: r.old[i].ID, JSONRequest: r.jobs[i].record})
+			puts = append(puts, i)
+		}
 	}
 
-	var recordsres RecordsRes
-	if err2 := json.Unmarshal(body2, &recordsres); err2 != nil {
-		log.Println(err2)
-		return fmt.Errorf("error parsing records response: %v", err2)
+	phaseStart := time.Now()
+	result, err := r.snapshot.batch(zone.ID, batch)
+	if err != nil {
+		err = fmt.Errorf("error writing records in %s: %v", zone.Name, err)
 	}
 
-	var recordid = ""
-
-	for _, record := range recordsres.Result {
-		if record.Type == "TLSA" && record.Name == portandprotocol+nameanddomain && record.Data.Usage == usage {
-			recordid = record.ID
+	for j, i := range puts {
+		if j >= len(result.Puts) {
+			r.fail(i, "", err)
+			continue
 		}
+		name, id := r.jobs[i].name(), r.old[i].ID
+		r.results[i] = newRecordResult(name, actionUpdate, id, nil)
+		slog.Info("Updated TLSA record", "name", name, "id", id)
+		markSynced(name)
+		notify(eventPublished, name, id, "Updated TLSA record", nil)
 	}
 
-	if recordid == "" {
-		log.Printf("Error: Could not find existing TLSA record with usage %d for %s%s\n",
-			usage, portandprotocol, nameanddomain)
-		return fmt.Errorf("could not find existing TLSA record with usage %d for %s%s", usage, portandprotocol, nameanddomain)
+	if len(posts) > 0 && len(result.Posts) > 0 {
+		observePhase("create", phaseStart)
 	}
-
-	puturl := "https://api.cloudflare.com/client/v4/zones/" + zoneID + "/dns_records/" + recordid
-
-	var jsonStr = []byte(putBody)
-	req3, err3 := http.NewRequest("PUT", puturl, bytes.NewBuffer(jsonStr))
-	if err3 != nil {
-		log.Println(err3)
-		return fmt.Errorf("error creating put request: %v", err3)
+	for j, i := range posts {
+		if j >= len(result.Posts) {
+			r.fail(i, "", err)
+			continue
+		}
+		name, id := r.jobs[i].name(), result.Posts[j].ID
+		r.newIDs[i] = id
+		r.results[i] = newRecordResult(name, actionRollover, id, nil)
+		slog.Info("Created new TLSA record, old record will be deleted after 2 TTL periods", "name", name, "id", id, "old_id", r.old[i].ID)
+		notify(eventPublished, name, id, "Created new TLSA record, old record will be deleted after 2 TTL periods", nil)
 	}
+}
 
-	req3.Header.Set("Content-Type", "application/json")
-	req3.Header.Add("Authorization", bearer)
+// checkPropagation marks the rollover of a job as safe to finish. If the new
+// record is not visible yet, both records are kept.
+func (r *updateRun) checkPropagation(i int) {
+	name := r.jobs[i].name()
 
-	client3 := &http.Client{}
-	resp3, err3 := client3.Do(req3)
-	if err3 != nil {
-		log.Println(err3)
-		return fmt.Errorf("error updating record: %v", err3)
-	}
-	defer resp3.Body.Close()
-
-	fmt.Println("Cloudflare Response Status:", resp3.Status)
-	return nil
+	phaseStart := time.Now()
+	err := propagationCheck(name)
+	observePhase("propagation_check", phaseStart)
+	if err != nil {
+		slog.Warn("DNS propagation check failed, preserving old TLSA record. Both old and new records will remain.", "name", name, "error", err)
+		notify(eventPropagationFailed, name, r.newIDs[i], "DNS propagation check failed", err)
+		notify(eventOldRecordPreserved, name, r.old[i].ID, "Old TLSA record preserved, both old and new records remain", err)
+		// Report failure, but do NOT delete the old record
+		// This ensures the server can continue using the existing certificate
+		// As requested in #35
+		r.fail(i, r.newIDs[i], fmt.Errorf("%w: %v - old record preserved for safety", errPropagation, err))
+		return
+	}
+	r.propagated[i] = true
 }
 
-func performRollover(portandprotocol string, nameanddomain string, putBody string) error {
-	url := "https://api.cloudflare.com/client/v4/zones"
-	bearer := "Bearer " + os.Getenv("TOKEN")
-
-	// Extract usage value from putBody
-	var jsonReq JSONRequest
-	if err := json.Unmarshal([]byte(putBody), &jsonReq); err != nil {
-		log.Printf("Error parsing request body: %v\n", err)
-		return fmt.Errorf("error parsing request body: %v", err)
+// deleteOld deletes the old records of the finished rollovers in a zone.
+func (r *updateRun) deleteOld(zone Zone) {
+	var batch recordBatch
+	var deletes []int
+	for _, i := range r.byZone[zone.ID] {
+		if r.propagated[i] {
+			batch.Deletes = append(batch.Deletes, batchDelete{ID: r.old[i].ID})
+			deletes = append(deletes, i)
+		}
 	}
-	usage := jsonReq.Data.Usage
-
-	// Get zone ID and old record first with the correct usage value
-	zoneID, oldRecord, err := getExistingRecord(url, bearer, portandprotocol, nameanddomain, usage)
-	if err != nil {
-		log.Printf("Error getting existing record: %v\n", err)
-		return err
+	if len(deletes) == 0 {
+		return
 	}
 
-	if zoneID == "" {
-		log.Println("Error: Could not find zone ID")
-		return fmt.Errorf("could not find zone ID")
+	phaseStart := time.Now()
+	result, err := r.snapshot.batch(zone.ID, batch)
+	if err == nil {
+		observePhase("delete", phaseStart)
 	}
-
-	// Store old record details
-	oldRecordID := ""
-	if oldRecord != nil {
-		oldRecordID = oldRecord.ID
-	} else {
-		return putToCloudflare(portandprotocol, nameanddomain, putBody)
+	for j, i := range deletes {
+		name := r.jobs[i].name()
+		if j >= len(result.Deletes) {
+			slog.Error("Error deleting old TLSA record", "name", name, "id", r.old[i].ID, "error", err)
+			r.fail(i, r.newIDs[i], err)
+			continue
+		}
+		markSynced(name)
+		notify(eventRolloverFinalized, name, r.newIDs[i], "Rollover finalized, old TLSA record deleted", nil)
 	}
+}
 
-	// Create new record first
-	createURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID)
-	jsonStr := []byte(putBody)
-	req, err := http.NewRequest("POST", createURL, bytes.NewBuffer(jsonStr))
-	if err != nil {
-		log.Printf("Error creating request: %v\n", err)
-		return fmt.Errorf("error creating request: %v", err)
+// runConcurrently calls fn for 0 to n-1 with at most limit calls running at
+// once, returning when all have finished.
+func runConcurrently(n, limit int, fn func(i int)) {
+	limit = max(1, min(limit, n))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range limit {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
 	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Add("Authorization", bearer)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// existingRecord returns the zone of the host and the TLSA record with the
+// given usage at name, or nil if there is none.
+func existingRecord(snapshot *providerSnapshot, name, nameanddomain string, usage int) (Zone, *DNSRecord, error) {
+	zone, err := snapshot.zoneFor(nameanddomain)
 	if err != nil {
-		log.Printf("Error creating new record: %v\n", err)
-		return fmt.Errorf("error creating new record: %v", err)
+		return zone, nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		log.Printf("Error creating new record. Status: %s\n", resp.Status)
-		return fmt.Errorf("error creating new record. Status: %s", resp.Status)
+	existing, err := snapshot.recordsAt(zone.ID, name)
+	if err != nil {
+		return zone, nil, err
 	}
-
-	ttl := time.Duration(oldRecord.TTL) * time.Second
-	if ttl == 0 {
-		ttl = 3600 * time.Second // Default to 1 hour if TTL is 0
+	var found *DNSRecord
+	for i := range existing {
+		if existing[i].Data.Usage == usage {
+			found = &existing[i]
+		}
 	}
+	return zone, found, nil
+}
 
-	// Create a channel to signal completion
-	done := make(chan error)
+// rolloverSleep and propagationCheck are replaced in tests.
+var (
+	rolloverSleep    = sleepContext
+	propagationCheck = checkDNSPropagation
+)
 
-	go func() {
-		// Wait for 2 rounds of TTL as per DANE certificate rollover best practices
-		waitTime := 2 * ttl
-		fmt.Printf("Waiting for %.0f seconds (2 TTL periods) to ensure DNS propagation...\n", waitTime.Seconds())
-		time.Sleep(waitTime)
-
-		// Check DNS propagation before deleting the old record
-		if err := checkDNSPropagation(portandprotocol + nameanddomain); err != nil {
-			log.Printf("Warning: DNS propagation check failed: %v\n", err)
-			log.Printf("Preserving old TLSA record to maintain service availability. Both old and new records will remain.\n")
-			// Return error to indicate failure, but do NOT delete the old record
-			// This ensures the server can continue using the existing certificate
-			// As requested in #35
-			done <- fmt.Errorf("DNS propagation check failed: %v - old record preserved for safety", err)
-			return
-		}
+// sleepContext waits for d, returning early with the error of ctx when it is
+// done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
-		if err := deleteRecord(zoneID, oldRecordID, bearer); err != nil {
-			log.Printf("Error deleting old record: %v\n", err)
-			done <- err
-			return
+// rolloverWait returns how long to keep the old record after publishing the
+// new one: two periods of the longer of the old and new TTL, so resolvers
+// holding either record set have expired it. An automatic TTL counts as
+// autoTTLSeconds.
+func rolloverWait(oldTTL, newTTL int) time.Duration {
+	seconds := func(ttl int) int {
+		switch ttl {
+		case 0:
+			return defaultTTL
+		case ttlAuto:
+			return autoTTLSeconds
 		}
-		done <- nil
-	}()
-
-	fmt.Printf("Created new TLSA record. Old record will be deleted in %.0f seconds\n", (2 * ttl).Seconds())
-
-	// Wait for deletion to complete
-	err = <-done
-	return err
+		return ttl
+	}
+	return 2 * time.Duration(max(seconds(oldTTL), seconds(newTTL))) * time.Second
 }
 
 // checkDNSPropagation verifies that DNS changes have propagated by querying multiple nameservers
@@ -403,7 +465,7 @@ func checkDNSPropagation(recordName string) error {
 		"208.67.222.222:53", // OpenDNS
 	}
 
-	fmt.Printf("Checking DNS propagation for %s against %d nameservers\n", recordName, len(nameservers))
+	slog.Info("Checking DNS propagation", "name", recordName, "nameservers", len(nameservers))
 
 	m := new(dns.Msg)
 	m.SetQuestion(dns.Fqdn(recordName), dns.TypeTLSA)
@@ -413,30 +475,28 @@ func checkDNSPropagation(recordName string) error {
 		c := new(dns.Client)
 		r, rtt, err := c.Exchange(m, ns)
 		if err != nil {
-			log.Printf("Failed to query %s: %v\n", ns, err)
 			return fmt.Errorf("DNS query to %s failed: %v", ns, err)
 		}
-		fmt.Printf("Successfully queried %s (response time: %v, answer sections: %d)\n",
-			ns, rtt, len(r.Answer))
+		slog.Debug("Queried nameserver", "nameserver", ns, "rtt", rtt, "answers", len(r.Answer))
 	}
 
 	return nil
 }
 
-func deleteRecord(zoneID, recordID, bearer string) error {
+func deleteRecord(zoneID, recordID string, auth cloudflareAuth) error {
 	if zoneID == "" || recordID == "" {
 		return fmt.Errorf("invalid zoneID or recordID")
 	}
 
-	deleteURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s", zoneID, recordID)
+	deleteURL := fmt.Sprintf(cloudflareAPI+"/zones/%s/dns_records/%s", zoneID, recordID)
 	req, err := http.NewRequest("DELETE", deleteURL, nil)
 	if err != nil {
 		return fmt.Errorf("error creating delete request: %v", err)
 	}
 
-	req.Header.Add("Authorization", bearer)
+	auth.apply(req)
 
-	client := &http.Client{}
+	client := cloudflareClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("error deleting record: %v", err)
@@ -447,87 +507,6 @@ func deleteRecord(zoneID, recordID, bearer string) error {
 		return fmt.Errorf("delete request failed with status: %s", resp.Status)
 	}
 
-	fmt.Printf("Deleted old TLSA record. Status: %s\n", resp.Status)
+	slog.Info("Deleted TLSA record", "id", recordID, "status", resp.Status)
 	return nil
 }
-
-func getExistingRecord(url, bearer, portandprotocol, nameanddomain string, usage int) (string, *DNSRecord, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Printf("Error creating request: %v\n", err)
-		return "", nil, fmt.Errorf("error creating request: %v", err)
-	}
-	req.Header.Add("Authorization", bearer)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Error getting zone info: %v\n", err)
-		return "", nil, fmt.Errorf("error getting zone info: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Error reading response: %v\n", err)
-		return "", nil, fmt.Errorf("error reading response: %v", err)
-	}
-
-	var res Res
-	if err := json.Unmarshal(body, &res); err != nil {
-		log.Printf("Error parsing zone response: %v\n", err)
-		return "", nil, fmt.Errorf("error parsing zone response: %v", err)
-	}
-
-	if len(res.Result) == 0 {
-		log.Println("No zones found")
-		return "", nil, fmt.Errorf("no zones found")
-	}
-
-	zoneID := ""
-	for _, zone := range res.Result {
-		if strings.HasSuffix(nameanddomain, zone.Name) {
-			zoneID = zone.ID
-		}
-	}
-
-	if zoneID == "" {
-		log.Println("No matching zones found")
-		return "", nil, fmt.Errorf("no matching zones found")
-	}
-
-	recordsURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID)
-	req2, err := http.NewRequest("GET", recordsURL, nil)
-	if err != nil {
-		log.Printf("Error creating records request: %v\n", err)
-		return zoneID, nil, fmt.Errorf("error creating records request: %v", err)
-	}
-	req2.Header.Add("Authorization", bearer)
-
-	resp2, err := client.Do(req2)
-	if err != nil {
-		log.Printf("Error getting DNS records: %v\n", err)
-		return zoneID, nil, fmt.Errorf("error getting DNS records: %v", err)
-	}
-	defer resp2.Body.Close()
-
-	body2, err := io.ReadAll(resp2.Body)
-	if err != nil {
-		log.Printf("Error reading records response: %v\n", err)
-		return zoneID, nil, fmt.Errorf("error reading records response: %v", err)
-	}
-
-	var recordsRes RecordsRes
-	if err := json.Unmarshal(body2, &recordsRes); err != nil {
-		log.Printf("Error parsing records response: %v\n", err)
-		return zoneID, nil, fmt.Errorf("error parsing records response: %v", err)
-	}
-
-	for _, record := range recordsRes.Result {
-		if record.Type == "TLSA" && record.Name == portandprotocol+nameanddomain && record.Data.Usage == usage {
-			return zoneID, &record, nil
-		}
-	}
-
-	return zoneID, nil, nil
-}
@@ -1,8 +1,13 @@
 package resource
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -197,17 +202,17 @@ func TestDeleteRecord_InvalidInputs(t *testing.T) {
 		name     string
 		zoneID   string
 		recordID string
-		bearer   string
+		auth     cloudflareAuth
 		wantErr  bool
 	}{
-		{"EmptyZoneID", "", "record-123", "Bearer token", true},
-		{"EmptyRecordID", "zone-123", "", "Bearer token", true},
-		{"BothEmpty", "", "", "Bearer token", true},
+		{"EmptyZoneID", "", "record-123", cloudflareAuth{token: "token"}, true},
+		{"EmptyRecordID", "zone-123", "", cloudflareAuth{token: "token"}, true},
+		{"BothEmpty", "", "", cloudflareAuth{token: "token"}, true},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := deleteRecord(tc.zoneID, tc.recordID, tc.bearer)
+			err := deleteRecord(tc.zoneID, tc.recordID, tc.auth)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("deleteRecord() error = %v, wantErr %v", err, tc.wantErr)
 			}
@@ -220,11 +225,11 @@ func TestDeleteRecord_ValidInputs(t *testing.T) {
 	// For now, we just test that valid inputs don't immediately error
 	zoneID := "valid-zone-123"
 	recordID := "valid-record-456"
-	bearer := "Bearer test-token"
+	auth := cloudflareAuth{token: "test-token"}
 
 	// We expect this to fail because it will try to make a real HTTP request
 	// In production, you would mock the HTTP client
-	err := deleteRecord(zoneID, recordID, bearer)
+	err := deleteRecord(zoneID, recordID, auth)
 	if err == nil {
 		t.Skip("Test requires HTTP mocking, skipping")
 	}
@@ -243,10 +248,193 @@ func addUpdateFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolP("no-dane-ee", "", false, "Do not update DANE-EE record")
 	cmd.Flags().BoolP("dane-ta", "", false, "Update DANE-TA record")
 	cmd.Flags().BoolP("rollover", "r", false, "Perform rolling update")
+	cmd.Flags().Bool("rollback-on-error", false, "Restore records on failure")
+	cmd.Flags().Int("concurrency", 4, "Number of records to update at once")
 	cmd.Flags().IntP("selector", "l", -1, "TLSA selector")
 	cmd.Flags().IntP("matching-type", "m", 1, "TLSA matching type")
 }
 
+func TestRunConcurrently(t *testing.T) {
+	var mu sync.Mutex
+	var running, peak int
+	called := make([]bool, 20)
+
+	runConcurrently(len(called), 3, func(i int) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		called[i] = true
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if peak > 3 {
+		t.Errorf("Expected at most 3 calls at once, got %d", peak)
+	}
+	for i, ok := range called {
+		if !ok {
+			t.Errorf("Index %d was not processed", i)
+		}
+	}
+}
+
+func TestRunUpdateAll_SharedRolloverWait(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = append(opts.Services, tlsaService{Port: "465", Protocol: "tcp"})
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, "0000", managedComment)
+	}
+	opts.TTL = 300
+
+	var waits []time.Duration
+	originalSleep, originalCheck := rolloverSleep, propagationCheck
+	rolloverSleep = func(_ context.Context, d time.Duration) error { waits = append(waits, d); return nil }
+	propagationCheck = func(string) error { return nil }
+	t.Cleanup(func() { rolloverSleep, propagationCheck = originalSleep, originalCheck })
+
+	results, err := runUpdateAll(context.Background(), []tlsaOptions{opts}, true, false, 2)
+	if err != nil {
+		t.Fatalf("runUpdateAll() error = %v", err)
+	}
+
+	// One wait for all three records, long enough for the old TTL of 3600
+	if len(waits) != 1 || waits[0] != 2*time.Hour {
+		t.Errorf("Expected a single 2h wait, got %v", waits)
+	}
+	if len(results) != 3 || results[2].Name != "_465._tcp.mail.example.com" || results[2].Action != actionRollover {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	for _, record := range f.zoneRecords("zone-1") {
+		if record.Data.Certificate == "0000" {
+			t.Errorf("Expected old record %s to be deleted", record.Name)
+		}
+	}
+	if n := len(f.zoneRecords("zone-1")); n != 3 {
+		t.Errorf("Expected 3 records after rollover, got %d", n)
+	}
+}
+
+func TestRunUpdateAll_ListsOnce(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = append(opts.Services, tlsaService{Port: "465", Protocol: "tcp"})
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, "0000", managedComment)
+	}
+
+	if _, err := runUpdateAll(context.Background(), []tlsaOptions{opts}, false, false, 3); err != nil {
+		t.Fatalf("runUpdateAll() error = %v", err)
+	}
+	if n := f.countCalls("GET /zones") - f.countCalls("GET /zones/"); n != 1 {
+		t.Errorf("Expected 1 zone listing, got %d", n)
+	}
+	if n := f.countCalls("GET /zones/zone-1/dns_records"); n != 1 {
+		t.Errorf("Expected 1 record listing, got %d", n)
+	}
+	if n := f.countCalls("POST /zones/zone-1/dns_records/batch"); n != 1 {
+		t.Errorf("Expected 1 batch, got %d", n)
+	}
+	if n := f.countCalls("PUT"); n != 0 {
+		t.Errorf("Expected no individual updates, got %d", n)
+	}
+}
+
+func TestRunUpdateAll_PropagationFailureKeepsOldRecords(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, "0000", managedComment)
+	}
+
+	originalSleep, originalCheck := rolloverSleep, propagationCheck
+	rolloverSleep = func(context.Context, time.Duration) error { return nil }
+	propagationCheck = func(name string) error {
+		if strings.HasPrefix(name, "_587.") {
+			return errors.New("not visible on 8.8.8.8:53")
+		}
+		return nil
+	}
+	t.Cleanup(func() { rolloverSleep, propagationCheck = originalSleep, originalCheck })
+
+	results, err := runUpdateAll(context.Background(), []tlsaOptions{opts}, true, false, 4)
+	if !errors.Is(err, errPropagation) {
+		t.Fatalf("Expected propagation error, got %v", err)
+	}
+	if results[0].Error != "" || results[1].Error == "" || results[1].ID == "" {
+		t.Errorf("Expected only the second record to fail, got %+v", results)
+	}
+	// The old record of port 587 is preserved next to the new one
+	if n := len(f.zoneRecords("zone-1")); n != 3 {
+		t.Errorf("Expected 3 records, got %d", n)
+	}
+}
+
+func TestRunUpdateAll_CancelledWaitKeepsOldRecords(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, "0000", managedComment)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	originalCheck := propagationCheck
+	propagationCheck = func(string) error {
+		t.Error("An interrupted rollover must not check propagation")
+		return nil
+	}
+	t.Cleanup(func() { propagationCheck = originalCheck })
+
+	// The real wait returns at once because ctx is already done
+	if _, err := runUpdateAll(ctx, []tlsaOptions{opts}, true, false, 2); err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("Expected interrupted rollover error, got %v", err)
+	}
+	if n := len(f.zoneRecords("zone-1")); n != 4 {
+		t.Errorf("Expected old and new records to remain, got %d", n)
+	}
+}
+
+func TestRunUpdateAll_MissingCertIsError(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	f.addRecord("zone-1", "_25._tcp.mail.example.com", 3, 1, 1, "00ff", "")
+	f.addRecord("zone-1", "_587._tcp.mail.example.com", 3, 1, 1, "00ff", "")
+	opts.Cert = filepath.Join(t.TempDir(), "missing.pem")
+
+	// watch relies on this returning so it can retry on the next event
+	if _, err := runUpdateAll(context.Background(), []tlsaOptions{opts}, false, false, 1); err == nil {
+		t.Error("Expected error for missing certificate")
+	}
+	if n := f.countCalls("POST") + f.countCalls("PUT"); n != 0 {
+		t.Errorf("Expected no writes, got %d", n)
+	}
+}
+
+func TestRolloverWait(t *testing.T) {
+	testCases := []struct {
+		oldTTL, newTTL int
+		want           time.Duration
+	}{
+		{3600, 3600, 2 * time.Hour},
+		{3600, 300, 2 * time.Hour},
+		{300, 300, 10 * time.Minute},
+		{ttlAuto, ttlAuto, 10 * time.Minute},
+		{0, 300, 2 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		if got := rolloverWait(tc.oldTTL, tc.newTTL); got != tc.want {
+			t.Errorf("rolloverWait(%d, %d) = %v, want %v", tc.oldTTL, tc.newTTL, got, tc.want)
+		}
+	}
+}
+
 func TestCheckDNSPropagation_InvalidDomain(t *testing.T) {
 	// Test with invalid domain - this may timeout or fail quickly
 	err := checkDNSPropagation("invalid..domain..test")
@@ -0,0 +1,246 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+func ResourceWatch(cmd *cobra.Command, args []string) error {
+	all, err := loadTLSAOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	rollover, err := cmd.Flags().GetBool("rollover")
+	if err != nil {
+		return err
+	}
+
+	rollback, err := cmd.Flags().GetBool("rollback-on-error")
+	if err != nil {
+		return err
+	}
+
+	concurrency, err := cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	debounce, err := cmd.Flags().GetDuration("debounce")
+	if err != nil {
+		return err
+	}
+
+	pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+	if err != nil {
+		return err
+	}
+
+	extraPaths, err := cmd.Flags().GetStringSlice("watch-path")
+	if err != nil {
+		return err
+	}
+
+	onStart, err := cmd.Flags().GetBool("on-start")
+	if err != nil {
+		return err
+	}
+
+	if err := configureNotifiers(cmd); err != nil {
+		return err
+	}
+
+	metricsListen, err := cmd.Flags().GetString("metrics-listen")
+	if err != nil {
+		return err
+	}
+	if _, err := serveMetrics(metricsListen); err != nil {
+		return err
+	}
+
+	if err := preflight(cmd, cloudflareCredentials(), optionHosts(all)); err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, opts := range all {
+		paths = append(paths, opts.Cert)
+	}
+
+	w := &certWatcher{
+		paths:        append(paths, extraPaths...),
+		debounce:     debounce,
+		pollInterval: pollInterval,
+		fingerprint: func() (string, error) {
+			var fp []string
+			for _, opts := range all {
+				if err := updateCertExpiry(opts.Cert); err != nil {
+					return "", err
+				}
+				eeHash, caHash, err := opts.hashes()
+				if err != nil {
+					return "", err
+				}
+				fp = append(fp, eeHash, caHash)
+			}
+			return strings.Join(fp, " "), nil
+		},
+		publish: func(ctx context.Context) error {
+			_, err := runUpdateAll(ctx, all, rollover, rollback, concurrency)
+			return err
+		},
+	}
+
+	if !onStart || recordsMatch(all) {
+		// Treat the certificate currently on disk as already published
+		if fp, err := w.fingerprint(); err == nil {
+			w.last = fp
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return w.run(ctx)
+}
+
+// recordsMatch reports whether every record the certificates call for is
+// already published, as check would find it. When that cannot be determined
+// the records are assumed not to match, so they are published.
+func recordsMatch(all []tlsaOptions) bool {
+	results, err := checkRecords(all, cloudflareCredentials())
+	if err != nil {
+		slog.Warn("Could not compare published TLSA records, publishing at startup", "error", err)
+		return false
+	}
+	for _, result := range results {
+		if result.Status == checkCritical {
+			return false
+		}
+	}
+	slog.Info("Published TLSA records match the certificate, nothing to publish at startup")
+	return true
+}
+
+// certWatcher republishes TLSA records when the certificate association data
+// computed from the watched files changes. Filesystem events are debounced so
+// a renewal that rewrites several files, or a single file in several writes,
+// results in exactly one publish of the final content.
+type certWatcher struct {
+	paths        []string
+	debounce     time.Duration
+	pollInterval time.Duration
+	fingerprint  func() (string, error)
+	publish      func(ctx context.Context) error
+	last         string
+}
+
+func (w *certWatcher) run(ctx context.Context) error {
+	var events <-chan fsnotify.Event
+	var watchErrors <-chan error
+
+	watched := make(map[string]bool)
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Filesystem notifications unavailable, polling only", "error", err)
+	} else {
+		defer fsw.Close()
+		dirs := make(map[string]bool)
+		for _, path := range w.paths {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("error resolving %s: %v", path, err)
+			}
+			watched[abs] = true
+			// Watch the directory rather than the file so replaced files and
+			// re-pointed symlinks (as certbot does in live/) are still seen
+			dirs[filepath.Dir(abs)] = true
+		}
+		for dir := range dirs {
+			if err := fsw.Add(dir); err != nil {
+				slog.Warn("Could not watch directory, polling only", "dir", dir, "error", err)
+			}
+		}
+		events = fsw.Events
+		watchErrors = fsw.Errors
+	}
+
+	var poll <-chan time.Time
+	if w.pollInterval > 0 {
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	debounce := time.NewTimer(w.debounce)
+	debounce.Stop()
+	pending := false
+
+	slog.Info("Watching for certificate changes", "paths", w.paths)
+	w.check(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if !watched[filepath.Clean(event.Name)] || event.Op == fsnotify.Chmod {
+				continue
+			}
+			debounce.Reset(w.debounce)
+			pending = true
+		case err, ok := <-watchErrors:
+			if !ok {
+				watchErrors = nil
+				continue
+			}
+			slog.Warn("Filesystem watch error", "error", err)
+		case <-debounce.C:
+			pending = false
+			w.check(ctx)
+		case <-poll:
+			if !pending {
+				w.check(ctx)
+			}
+		}
+	}
+}
+
+// check publishes if the current fingerprint differs from the last one
+// published. A fingerprint that cannot be computed (e.g. a half-written PEM
+// file) is skipped; the next event or poll tick will try again. ctx is passed
+// to publish so a stop signal ends a rollover wait.
+func (w *certWatcher) check(ctx context.Context) {
+	fp, err := w.fingerprint()
+	if err != nil {
+		slog.Warn("Skipping publish, certificate not readable", "error", err)
+		return
+	}
+	if fp == w.last {
+		return
+	}
+
+	slog.Info("Certificate change detected, updating TLSA records")
+	if err := w.publish(ctx); err != nil {
+		slog.Error("Error publishing TLSA records, will retry", "error", err)
+		return
+	}
+	w.last = fp
+}
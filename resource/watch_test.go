@@ -0,0 +1,241 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCertWatcher_CheckPublishesOnChange(t *testing.T) {
+	fp := "a"
+	published := 0
+
+	w := &certWatcher{
+		fingerprint: func() (string, error) { return fp, nil },
+		publish: func(context.Context) error {
+			published++
+			return nil
+		},
+		last: "a",
+	}
+
+	w.check(context.Background())
+	if published != 0 {
+		t.Errorf("Expected no publish for unchanged fingerprint, got %d", published)
+	}
+
+	fp = "b"
+	w.check(context.Background())
+	if published != 1 {
+		t.Errorf("Expected 1 publish after change, got %d", published)
+	}
+
+	w.check(context.Background())
+	if published != 1 {
+		t.Errorf("Expected no further publish once published, got %d", published)
+	}
+}
+
+func TestCertWatcher_CheckSkipsUnreadable(t *testing.T) {
+	published := 0
+
+	w := &certWatcher{
+		fingerprint: func() (string, error) { return "", errors.New("failed to parse pem file") },
+		publish: func(context.Context) error {
+			published++
+			return nil
+		},
+	}
+
+	w.check(context.Background())
+	if published != 0 {
+		t.Errorf("Expected no publish for unreadable certificate, got %d", published)
+	}
+}
+
+func TestCertWatcher_CheckRetriesAfterFailure(t *testing.T) {
+	fail := true
+	published := 0
+
+	w := &certWatcher{
+		fingerprint: func() (string, error) { return "new", nil },
+		publish: func(context.Context) error {
+			published++
+			if fail {
+				return errors.New("cloudflare unavailable")
+			}
+			return nil
+		},
+		last: "old",
+	}
+
+	w.check(context.Background())
+	if w.last != "old" {
+		t.Errorf("Expected last fingerprint to be kept after failed publish, got %s", w.last)
+	}
+
+	fail = false
+	w.check(context.Background())
+	if published != 2 {
+		t.Errorf("Expected publish to be retried, got %d attempts", published)
+	}
+	if w.last != "new" {
+		t.Errorf("Expected last fingerprint 'new', got %s", w.last)
+	}
+}
+
+func TestCertWatcher_RunDebouncesWrites(t *testing.T) {
+	certPath := generateTestCertForReq(t)
+	opts := tlsaOptions{Cert: certPath, DaneEE: true, Selector: -1, MatchingType: 1}
+	initial, _, err := opts.hashes()
+	if err != nil {
+		t.Fatalf("Failed to hash test certificate: %v", err)
+	}
+
+	var mu sync.Mutex
+	published := 0
+
+	w := &certWatcher{
+		paths:    []string{certPath},
+		debounce: 200 * time.Millisecond,
+		fingerprint: func() (string, error) {
+			ee, _, err := opts.hashes()
+			return ee, err
+		},
+		publish: func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			published++
+			return nil
+		},
+		last: initial,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.run(ctx) }()
+
+	// Give the watcher time to register before writing
+	time.Sleep(100 * time.Millisecond)
+
+	// Simulate a renewal writing the new certificate in several chunks
+	newCert := generateTestCertForReq(t)
+	content, err := os.ReadFile(newCert)
+	if err != nil {
+		t.Fatalf("Failed to read new certificate: %v", err)
+	}
+	half := len(content) / 2
+	if err := os.WriteFile(certPath, content[:half], 0o644); err != nil {
+		t.Fatalf("Failed to write partial certificate: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	f, err := os.OpenFile(certPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open certificate: %v", err)
+	}
+	f.Write(content[half:])
+	f.Close()
+
+	time.Sleep(600 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if published != 1 {
+		t.Errorf("Expected exactly 1 publish after debounced writes, got %d", published)
+	}
+}
+
+func TestCertWatcher_RunPollsWithoutEvents(t *testing.T) {
+	fp := "old"
+	var mu sync.Mutex
+	published := 0
+
+	w := &certWatcher{
+		paths:        []string{filepath.Join(t.TempDir(), "missing.pem")},
+		debounce:     time.Second,
+		pollInterval: 50 * time.Millisecond,
+		fingerprint: func() (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return fp, nil
+		},
+		publish: func(context.Context) error {
+			published++
+			return nil
+		},
+		last: "old",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.run(ctx) }()
+
+	mu.Lock()
+	fp = "new"
+	mu.Unlock()
+
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	<-done
+
+	if published != 1 {
+		t.Errorf("Expected polling to publish once, got %d", published)
+	}
+}
+
+func TestResourceWatch_Validation(t *testing.T) {
+	certPath := generateTestCertForReq(t)
+
+	cmd := &cobra.Command{}
+	addCreateFlags(cmd)
+	cmd.Flags().Bool("rollover", false, "Perform rolling update")
+	cmd.Flags().Bool("rollback-on-error", false, "Restore records on failure")
+	cmd.Flags().Int("concurrency", 4, "Number of records to update at once")
+	cmd.Flags().StringSlice("watch-path", nil, "Additional file to watch")
+	cmd.Flags().Duration("debounce", time.Second, "Debounce")
+	cmd.Flags().Duration("poll-interval", time.Minute, "Poll interval")
+	cmd.Flags().Bool("on-start", false, "Publish at startup")
+
+	err := cmd.ParseFlags([]string{
+		"--url", "example.com",
+		"--subdomain", "mail",
+		"--cert", certPath,
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	err = ResourceWatch(cmd, []string{})
+	if err == nil {
+		t.Fatal("Expected error when no port is specified, got nil")
+	}
+}
+
+func TestRecordsMatch(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	opts := syncTestOptions(t)
+	if recordsMatch([]tlsaOptions{opts}) {
+		t.Error("Expected missing records not to match")
+	}
+
+	eeHash, _, err := opts.hashes()
+	if err != nil {
+		t.Fatalf("hashes() error = %v", err)
+	}
+	for _, svc := range opts.Services {
+		f.addRecord("zone-1", svc.prefix()+"mail.example.com", 3, 1, 1, eeHash, managedComment)
+	}
+	if !recordsMatch([]tlsaOptions{opts}) {
+		t.Error("Expected published records to match, --on-start must not rewrite them")
+	}
+}
@@ -0,0 +1,503 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// fileZoneID is the zone ID of the zone kept in a zone file.
+const fileZoneID = "zone-file"
+
+// fileProvider keeps TLSA records in a zone file, either a full zone with an
+// SOA record or a snippet pulled into one with $INCLUDE. Records are written
+// one per line with their comment as a zone-file comment, and only the lines
+// of changed records are touched. The SOA serial of a full zone is bumped on
+// every change. Record IDs are derived from the owner name and data, so an
+// updated record gets a new ID.
+type fileProvider struct {
+	path   string
+	origin string
+	mu     sync.Mutex
+}
+
+func newFileProvider(path, origin string) *fileProvider {
+	return &fileProvider{path: path, origin: strings.TrimSuffix(strings.ToLower(origin), ".")}
+}
+
+// zoneFile is a parsed zone file. origin is the zone name, endOrigin the
+// $ORIGIN in effect at the end of the file, where new records are added.
+type zoneFile struct {
+	lines     []string
+	origin    string
+	endOrigin string
+	soa       *fileSOA
+	records   []fileRecord
+	entries   []zoneEntry
+}
+
+// zoneEntry is any record of a zone file and the lines it spans. owner is its
+// absolute owner name, and explicit reports whether the record names it
+// rather than inheriting it from the record above with a blank owner.
+type zoneEntry struct {
+	start, end int
+	owner      string
+	explicit   bool
+}
+
+// fileSOA is the SOA record of a full zone and the lines it spans.
+type fileSOA struct {
+	start, end int
+	serial     uint32
+}
+
+// fileRecord is a TLSA record and the lines it spans. origin is the $ORIGIN
+// in effect there, used when the record is written back.
+type fileRecord struct {
+	start, end int
+	origin     string
+	record     DNSRecord
+}
+
+var tagsPattern = regexp.MustCompile(`\s*\[tags=([^\]]*)\]$`)
+
+func (p *fileProvider) zones() ([]Zone, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	zf, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	var zone Zone
+	zone.ID = fileZoneID
+	zone.Name = zf.origin
+	return []Zone{zone}, nil
+}
+
+func (p *fileProvider) tlsaRecords(zoneID string) ([]DNSRecord, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	zf, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	var records []DNSRecord
+	for _, r := range zf.records {
+		records = append(records, r.record)
+	}
+	return records, nil
+}
+
+// apply makes the changes of the batch in the order Cloudflare would and
+// writes the file once, so either every change is made or none is.
+func (p *fileProvider) apply(zoneID string, batch recordBatch) (*batchResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if batch.empty() {
+		return &batchResult{}, nil
+	}
+	if zoneID != fileZoneID {
+		return &batchResult{}, fmt.Errorf("unknown zone %s in zone file %s", zoneID, p.path)
+	}
+
+	zf, err := p.load()
+	if err != nil {
+		return &batchResult{}, err
+	}
+
+	type edit struct {
+		start, end int
+		lines      []string
+		owner      string
+	}
+	var edits []edit
+	result := &batchResult{}
+	changed := make(map[string]bool)
+	find := func(id string) (fileRecord, error) {
+		i := slices.IndexFunc(zf.records, func(r fileRecord) bool { return r.record.ID == id })
+		if i < 0 || changed[id] {
+			return fileRecord{}, fmt.Errorf("record %s not found in %s", id, p.path)
+		}
+		changed[id] = true
+		return zf.records[i], nil
+	}
+
+	for _, d := range batch.Deletes {
+		r, err := find(d.ID)
+		if err != nil {
+			return &batchResult{}, err
+		}
+		edits = append(edits, edit{start: r.start, end: r.end})
+		result.Deletes = append(result.Deletes, r.record)
+	}
+	for _, put := range batch.Puts {
+		r, err := find(put.ID)
+		if err != nil {
+			return &batchResult{}, err
+		}
+		record, err := fileRecordFromRequest(put.JSONRequest, zf.origin)
+		if err != nil {
+			return &batchResult{}, err
+		}
+		edits = append(edits, edit{start: r.start, end: r.end, lines: []string{zoneFileLine(record, r.origin)}, owner: record.Name})
+		result.Puts = append(result.Puts, record)
+	}
+	var added []string
+	if len(zf.lines) == 0 {
+		added = append(added, "$ORIGIN "+dns.Fqdn(zf.origin))
+	}
+	for _, req := range batch.Posts {
+		record, err := fileRecordFromRequest(req, zf.origin)
+		if err != nil {
+			return &batchResult{}, err
+		}
+		added = append(added, zoneFileLine(record, zf.endOrigin))
+		result.Posts = append(result.Posts, record)
+	}
+
+	// Records below an edited one with a blank owner inherited its owner.
+	// Name that owner on the first of them that is kept, so they stay where
+	// they are.
+	lines := slices.Clone(zf.lines)
+	edited := make(map[int]edit)
+	for _, e := range edits {
+		edited[e.start] = e
+	}
+	for i, entry := range zf.entries {
+		e, ok := edited[entry.start]
+		if !ok || !entry.explicit || e.owner == entry.owner {
+			continue
+		}
+		for _, next := range zf.entries[i+1:] {
+			if next.explicit {
+				break
+			}
+			if e, ok := edited[next.start]; !ok {
+				lines[next.start] = dns.Fqdn(entry.owner) + lines[next.start]
+				break
+			} else if e.lines != nil {
+				// A replaced record names its owner itself
+				break
+			}
+		}
+	}
+
+	if zf.soa != nil {
+		soaLines, err := bumpSerial(lines[zf.soa.start:zf.soa.end], zf.soa.serial, nextSerial(zf.soa.serial, time.Now()))
+		if err != nil {
+			return &batchResult{}, err
+		}
+		edits = append(edits, edit{start: zf.soa.start, end: zf.soa.end, lines: soaLines})
+	}
+
+	// Edit from the end of the file so earlier line numbers stay valid
+	slices.SortFunc(edits, func(a, b edit) int { return b.start - a.start })
+	for _, e := range edits {
+		lines = slices.Replace(lines, e.start, e.end, e.lines...)
+	}
+	lines = append(lines, added...)
+
+	if err := writeFileAtomic(p.path, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		return &batchResult{}, err
+	}
+	return result, nil
+}
+
+// load reads and parses the zone file. A missing file is an empty zone,
+// which needs --zone-origin.
+func (p *fileProvider) load() (*zoneFile, error) {
+	content, err := os.ReadFile(p.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading zone file: %v", err)
+	}
+
+	zf, err := parseZoneFile(string(content), p.origin, p.path)
+	if err != nil {
+		return nil, err
+	}
+	if zf.origin == "" {
+		return nil, fmt.Errorf("zone origin of %s is unknown, set --zone-origin", p.path)
+	}
+	return zf, nil
+}
+
+// parseZoneFile finds the SOA and TLSA records of a zone file, following
+// $ORIGIN and $TTL. Other records are kept as they are without being parsed.
+// The zone name is origin if set, else the owner of the SOA record, else the
+// first $ORIGIN.
+func parseZoneFile(content, origin, path string) (*zoneFile, error) {
+	zf := &zoneFile{}
+	if content != "" {
+		zf.lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	current, ttl, owner := origin, "", ""
+	firstOrigin := ""
+	for i := 0; i < len(zf.lines); {
+		text := stripZoneComment(zf.lines[i])
+		fields := strings.Fields(text)
+
+		// A record continues over the following lines while a parenthesis
+		// is open
+		end := i + 1
+		for depth := strings.Count(text, "(") - strings.Count(text, ")"); depth > 0 && end < len(zf.lines); end++ {
+			next := stripZoneComment(zf.lines[end])
+			depth += strings.Count(next, "(") - strings.Count(next, ")")
+		}
+		if len(fields) == 0 {
+			i = end
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) > 1 {
+				current = absoluteName(fields[1], current)
+				if firstOrigin == "" {
+					firstOrigin = current
+				}
+			}
+		case "$TTL":
+			if len(fields) > 1 {
+				ttl = fields[1]
+			}
+		case "$INCLUDE", "$GENERATE":
+			// Not followed, only this file is managed
+		default:
+			chunk := strings.Join(zf.lines[i:end], "\n")
+			explicit := !strings.HasPrefix(chunk, " ") && !strings.HasPrefix(chunk, "\t")
+			if explicit {
+				owner = fields[0]
+			} else {
+				chunk = owner + chunk
+			}
+			zf.entries = append(zf.entries, zoneEntry{start: i, end: end, owner: absoluteName(owner, current), explicit: explicit})
+			if !slices.ContainsFunc(fields, func(f string) bool { return strings.EqualFold(f, "TLSA") || strings.EqualFold(f, "SOA") }) {
+				break
+			}
+
+			rr, comment, err := parseZoneRecord(chunk, current, ttl, path)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: %v", path, i+1, err)
+			}
+			switch rr := rr.(type) {
+			case *dns.SOA:
+				zf.soa = &fileSOA{start: i, end: end, serial: rr.Serial}
+				if origin == "" {
+					origin = strings.TrimSuffix(strings.ToLower(rr.Hdr.Name), ".")
+				}
+			case *dns.TLSA:
+				zf.records = append(zf.records, fileRecord{start: i, end: end, origin: current, record: tlsaRecordFromRR(rr, comment)})
+			}
+		}
+		i = end
+	}
+
+	zf.origin = origin
+	if zf.origin == "" {
+		zf.origin = firstOrigin
+	}
+	zf.endOrigin = current
+	if zf.endOrigin == "" {
+		zf.endOrigin = zf.origin
+	}
+	return zf, nil
+}
+
+// parseZoneRecord parses one record with the $ORIGIN and $TTL in effect.
+func parseZoneRecord(chunk, origin, ttl, path string) (dns.RR, string, error) {
+	var input strings.Builder
+	if origin != "" {
+		input.WriteString("$ORIGIN " + dns.Fqdn(origin) + "\n")
+	}
+	if ttl != "" {
+		input.WriteString("$TTL " + ttl + "\n")
+	}
+	input.WriteString(chunk + "\n")
+
+	zp := dns.NewZoneParser(strings.NewReader(input.String()), "", path)
+	rr, ok := zp.Next()
+	if !ok {
+		if err := zp.Err(); err != nil {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("no record found")
+	}
+	return rr, strings.TrimSpace(strings.TrimPrefix(zp.Comment(), ";")), nil
+}
+
+func tlsaRecordFromRR(rr *dns.TLSA, comment string) DNSRecord {
+	var record DNSRecord
+	record.ZoneID = fileZoneID
+	record.Name = strings.TrimSuffix(strings.ToLower(rr.Hdr.Name), ".")
+	record.Type = "TLSA"
+	record.TTL = int(rr.Hdr.Ttl)
+	record.Data.Usage = int(rr.Usage)
+	record.Data.Selector = int(rr.Selector)
+	record.Data.MatchingType = int(rr.MatchingType)
+	record.Data.Certificate = strings.ToLower(rr.Certificate)
+	if m := tagsPattern.FindStringSubmatch(comment); m != nil {
+		record.Tags = strings.Split(m[1], ",")
+		comment = comment[:len(comment)-len(m[0])]
+	}
+	record.Comment = comment
+	record.ID = fileRecordID(record)
+	return record
+}
+
+// fileRecordFromRequest returns the record a request describes. As with
+// Cloudflare, a name not ending in the zone name is relative to the zone.
+func fileRecordFromRequest(req JSONRequest, zone string) (DNSRecord, error) {
+	if req.Type != "TLSA" {
+		return DNSRecord{}, fmt.Errorf("zone file provider only writes TLSA records, got %s", req.Type)
+	}
+	if req.Ttl == ttlAuto {
+		return DNSRecord{}, fmt.Errorf("automatic TTL is not supported in zone files, set --ttl")
+	}
+
+	var record DNSRecord
+	record.ZoneID = fileZoneID
+	record.Name = strings.TrimSuffix(strings.ToLower(req.Name), ".")
+	if record.Name != zone && !strings.HasSuffix(record.Name, "."+zone) {
+		record.Name += "." + zone
+	}
+	record.Type = req.Type
+	record.TTL = req.Ttl
+	if record.TTL == 0 {
+		record.TTL = defaultTTL
+	}
+	record.Data.Usage = req.Data.Usage
+	record.Data.Selector = req.Data.Selector
+	record.Data.MatchingType = req.Data.Matchingtype
+	record.Data.Certificate = strings.ToLower(req.Data.Certificate)
+	record.Comment = strings.Join(strings.Fields(req.Comment), " ")
+	record.Tags = req.Tags
+	record.ID = fileRecordID(record)
+	return record, nil
+}
+
+// fileRecordID identifies a record in a zone file by its owner and data.
+func fileRecordID(record DNSRecord) string {
+	sum := sha256.Sum256([]byte(record.Name + " " + formatTLSAData(record.Data.Usage, record.Data.Selector, record.Data.MatchingType, record.Data.Certificate)))
+	return "file-" + hex.EncodeToString(sum[:8])
+}
+
+// zoneFileLine writes the record on one line, named relative to origin, with
+// its comment and tags as a zone-file comment.
+func zoneFileLine(record DNSRecord, origin string) string {
+	exported := exportedRecord{
+		Zone:         origin,
+		Name:         record.Name,
+		TTL:          record.TTL,
+		Usage:        record.Data.Usage,
+		Selector:     record.Data.Selector,
+		MatchingType: record.Data.MatchingType,
+		Certificate:  record.Data.Certificate,
+	}
+	line := exported.tlsaRR().String()
+
+	comment := record.Comment
+	if len(record.Tags) > 0 {
+		comment = strings.TrimSpace(comment + " [tags=" + strings.Join(record.Tags, ",") + "]")
+	}
+	if comment != "" {
+		line += " ; " + comment
+	}
+	return line
+}
+
+// nextSerial returns the SOA serial after old. Serials in the YYYYMMDDnn
+// convention move to today's date, others are incremented.
+func nextSerial(old uint32, now time.Time) uint32 {
+	today := uint32(now.Year()*1000000 + int(now.Month())*10000 + now.Day()*100)
+	if old >= 1970010100 && old < 2100000000 && old < today {
+		return today
+	}
+	return old + 1
+}
+
+var soaPattern = regexp.MustCompile(`(?i)\bSOA\b`)
+
+// bumpSerial replaces the serial in the lines of an SOA record, the first
+// number after the SOA type equal to it.
+func bumpSerial(lines []string, old, serial uint32) ([]string, error) {
+	text := strings.Join(lines, "\n")
+	loc := soaPattern.FindStringIndex(text)
+	if loc != nil {
+		serialPattern := regexp.MustCompile(`\b` + strconv.FormatUint(uint64(old), 10) + `\b`)
+		if m := serialPattern.FindStringIndex(text[loc[1]:]); m != nil {
+			start, end := loc[1]+m[0], loc[1]+m[1]
+			text = text[:start] + strconv.FormatUint(uint64(serial), 10) + text[end:]
+			return strings.Split(text, "\n"), nil
+		}
+	}
+	return nil, fmt.Errorf("could not find SOA serial %d to update", old)
+}
+
+// stripZoneComment removes a ; comment outside quotes from a zone file line.
+func stripZoneComment(line string) string {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"' && (i == 0 || line[i-1] != '\\'):
+			quoted = !quoted
+		case c == ';' && !quoted:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// absoluteName returns name made absolute against origin, without the
+// trailing dot.
+func absoluteName(name, origin string) string {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".") {
+		return strings.TrimSuffix(name, ".")
+	}
+	if name == "@" {
+		return origin
+	}
+	return name + "." + origin
+}
+
+// writeFileAtomic replaces the file through a temporary file in the same
+// directory, keeping its permissions.
+func writeFileAtomic(path string, content []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing zone file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing zone file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing zone file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("error writing zone file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing zone file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package resource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testZone = `$TTL 3600
+@	IN	SOA	ns1.example.com. hostmaster.example.com. (
+		2024010101 ; serial
+		3600 900 604800 300 )
+	IN	NS	ns1.example.com.
+www	IN	A	192.0.2.1
+_25._tcp.mail	IN	TLSA	3 1 1 AAAA ; ` + managedComment + `
+	IN	TLSA	2 0 1 bbbb
+`
+
+func useZoneFile(t *testing.T, content, origin string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "example.com.zone")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o640); err != nil {
+			t.Fatalf("Failed to write zone file: %v", err)
+		}
+	}
+	activeProvider = newFileProvider(path, origin)
+	t.Cleanup(func() { activeProvider = nil })
+	return path
+}
+
+func readZoneFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read zone file: %v", err)
+	}
+	return string(content)
+}
+
+func TestParseZoneFile(t *testing.T) {
+	zf, err := parseZoneFile(testZone, "example.com", "test.zone")
+	if err != nil {
+		t.Fatalf("parseZoneFile() error = %v", err)
+	}
+	if zf.soa == nil || zf.soa.serial != 2024010101 || zf.soa.start != 1 || zf.soa.end != 4 {
+		t.Errorf("Unexpected SOA: %+v", zf.soa)
+	}
+	if len(zf.records) != 2 {
+		t.Fatalf("Expected 2 TLSA records, got %d", len(zf.records))
+	}
+
+	ee, ta := zf.records[0].record, zf.records[1].record
+	if ee.Name != "_25._tcp.mail.example.com" || ee.TTL != 3600 || ee.Data.Certificate != "aaaa" || !isManaged(ee) {
+		t.Errorf("Unexpected DANE-EE record: %+v", ee)
+	}
+	// The second record inherits the owner of the line above
+	if ta.Name != ee.Name || ta.Data.Usage != 2 || isManaged(ta) || ta.ID == ee.ID {
+		t.Errorf("Unexpected DANE-TA record: %+v", ta)
+	}
+
+	// Without --zone-origin the SOA owner names the zone
+	if zf, err := parseZoneFile("example.org. 3600 IN SOA ns1.example.org. hostmaster.example.org. 1 2 3 4 5\n", "", "test.zone"); err != nil || zf.origin != "example.org" {
+		t.Errorf("Expected origin from SOA, got %+v (%v)", zf, err)
+	}
+	if _, err := parseZoneFile("_25._tcp.mail IN TLSA 3 1 1 aaaa\n", "", "test.zone"); err == nil {
+		t.Error("Expected error for relative name without origin")
+	}
+}
+
+func TestZoneFileLine_RoundTrip(t *testing.T) {
+	record, err := fileRecordFromRequest(JSONRequest{
+		Type:    "TLSA",
+		Name:    "_25._tcp.mail",
+		Data:    Data{Usage: 3, Selector: 1, Matchingtype: 1, Certificate: "ABCD"},
+		Ttl:     300,
+		Comment: "Updated by GoTLSAFlare\n- today",
+		Tags:    []string{"env:prod", "team:mail"},
+	}, "example.com")
+	if err != nil {
+		t.Fatalf("fileRecordFromRequest() error = %v", err)
+	}
+
+	line := zoneFileLine(record, "example.com")
+	if !strings.HasPrefix(line, "_25._tcp.mail\t300\tIN\tTLSA\t3 1 1 abcd ; Updated by GoTLSAFlare - today [tags=env:prod,team:mail]") {
+		t.Errorf("Unexpected zone file line: %s", line)
+	}
+
+	zf, err := parseZoneFile(line+"\n", "example.com", "test.zone")
+	if err != nil || len(zf.records) != 1 {
+		t.Fatalf("Written line does not parse back: %v", err)
+	}
+	parsed := zf.records[0].record
+	if parsed.ID != record.ID || parsed.Comment != record.Comment || !sameRecordSettings(JSONRequest{Ttl: 300, Tags: record.Tags}, parsed) {
+		t.Errorf("Round trip changed the record: %+v != %+v", parsed, record)
+	}
+
+	if _, err := fileRecordFromRequest(JSONRequest{Type: "TLSA", Name: "_25._tcp.mail", Ttl: ttlAuto}, "example.com"); err == nil {
+		t.Error("Expected error for automatic TTL")
+	}
+}
+
+func TestNextSerial(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		old, want uint32
+	}{
+		{2024010101, 2026030400},
+		{2026030400, 2026030401},
+		{2026030499, 2026030500},
+		{42, 43},
+	}
+
+	for _, tc := range testCases {
+		if got := nextSerial(tc.old, now); got != tc.want {
+			t.Errorf("nextSerial(%d) = %d, want %d", tc.old, got, tc.want)
+		}
+	}
+}
+
+func TestFileProvider_UpdatePatchesZone(t *testing.T) {
+	path := useZoneFile(t, testZone, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = opts.Services[:1]
+
+	if _, err := runUpdateAll(context.Background(), []tlsaOptions{opts}, false, false, 1); err != nil {
+		t.Fatalf("runUpdateAll() error = %v", err)
+	}
+
+	content := readZoneFile(t, path)
+	for _, keep := range []string{"www\tIN\tA\t192.0.2.1", "\tIN\tTLSA\t2 0 1 bbbb", "\t3600 900 604800 300 )"} {
+		if !strings.Contains(content, keep) {
+			t.Errorf("Expected %q to be kept:\n%s", keep, content)
+		}
+	}
+	if strings.Contains(content, "AAAA") || !strings.Contains(content, "_25._tcp.mail\t3600\tIN\tTLSA\t3 1 1 ") {
+		t.Errorf("Expected the DANE-EE record to be replaced:\n%s", content)
+	}
+	if strings.Contains(content, "2024010101") {
+		t.Errorf("Expected the SOA serial to be bumped:\n%s", content)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("Expected file mode to be kept, got %v", info.Mode())
+	}
+}
+
+func TestFileProvider_CreateSnippetThenSync(t *testing.T) {
+	path := useZoneFile(t, "", "example.com")
+	opts := syncTestOptions(t)
+
+	if _, err := runCreateAll([]tlsaOptions{opts}); err != nil {
+		t.Fatalf("runCreateAll() error = %v", err)
+	}
+	content := readZoneFile(t, path)
+	if !strings.HasPrefix(content, "$ORIGIN example.com.\n") || strings.Count(content, "\tIN\tTLSA\t") != 2 {
+		t.Errorf("Unexpected snippet:\n%s", content)
+	}
+
+	changes, err := planSync([]tlsaOptions{opts}, cloudflareCredentials(), false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if counts := countActions(changes); counts[actionNoop] != 2 || len(changes) != 2 {
+		t.Errorf("Expected sync to find the snippet up to date, got %v", counts)
+	}
+}
+
+func TestFileProvider_Rollover(t *testing.T) {
+	path := useZoneFile(t, testZone, "example.com")
+	opts := syncTestOptions(t)
+	opts.Services = opts.Services[:1]
+
+	originalSleep := rolloverSleep
+	rolloverSleep = func(context.Context, time.Duration) error {
+		t.Error("A zone file rollover must not wait")
+		return nil
+	}
+	t.Cleanup(func() { rolloverSleep = originalSleep })
+
+	if _, err := runUpdateAll(context.Background(), []tlsaOptions{opts}, true, false, 1); err != nil {
+		t.Fatalf("runUpdateAll() error = %v", err)
+	}
+
+	// Both records are kept until the deployed zone is synced
+	during := readZoneFile(t, path)
+	if !strings.Contains(during, "AAAA") || strings.Count(during, "TLSA\t3 1 1") != 2 {
+		t.Errorf("Expected old and new records after the update:\n%s", during)
+	}
+
+	changes, err := planSync([]tlsaOptions{opts}, cloudflareCredentials(), false)
+	if err != nil {
+		t.Fatalf("planSync() error = %v", err)
+	}
+	if err := applyChanges(changes, cloudflareCredentials()); err != nil {
+		t.Fatalf("applyChanges() error = %v", err)
+	}
+	after := readZoneFile(t, path)
+	if strings.Contains(after, "AAAA") || strings.Count(after, "TLSA\t3 1 1") != 1 {
+		t.Errorf("Expected sync to remove the old record:\n%s", after)
+	}
+}
+
+func TestFileProvider_DeleteKeepsInheritedOwners(t *testing.T) {
+	const zone = `$TTL 3600
+@	IN	SOA	ns1.example.com. hostmaster.example.com. 2024010101 3600 900 604800 300
+mail	IN	A	192.0.2.1
+_25._tcp.mail	IN	TLSA	3 1 1 aaaa
+	IN	TLSA	2 0 1 bbbb
+_dmarc	IN	TXT	"v=DMARC1; p=none"
+	IN	TXT	"second"
+_465._tcp.mail	IN	TLSA	3 1 1 cccc
+	IN	TXT	"note"
+`
+	path := useZoneFile(t, zone, "example.com")
+	zf, err := parseZoneFile(zone, "example.com", path)
+	if err != nil {
+		t.Fatalf("parseZoneFile() error = %v", err)
+	}
+	ee, other := zf.records[0].record, zf.records[2].record
+
+	batch := recordBatch{Deletes: []batchDelete{{ID: ee.ID}, {ID: other.ID}}}
+	if _, err := activeProvider.apply(fileZoneID, batch); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+
+	// The DANE-TA record must not move to mail.example.com
+	records, err := activeProvider.tlsaRecords(fileZoneID)
+	if err != nil {
+		t.Fatalf("tlsaRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].Name != "_25._tcp.mail.example.com" || records[0].Data.Usage != 2 {
+		t.Errorf("Expected the DANE-TA record to keep its owner, got %+v", records)
+	}
+	content := readZoneFile(t, path)
+	if !strings.Contains(content, "\n_25._tcp.mail.example.com.\tIN\tTLSA\t2 0 1 bbbb\n") || !strings.Contains(content, "\n\tIN\tTXT\t\"second\"\n") ||
+		!strings.Contains(content, "\n_465._tcp.mail.example.com.\tIN\tTXT\t\"note\"\n") {
+		t.Errorf("Expected only the continuation line to gain an owner:\n%s", content)
+	}
+}
+
+func TestFileProvider_FailedBatchLeavesFile(t *testing.T) {
+	path := useZoneFile(t, testZone, "example.com")
+
+	_, err := activeProvider.apply(fileZoneID, recordBatch{Deletes: []batchDelete{{ID: "file-missing"}}})
+	if err == nil {
+		t.Fatal("Expected error for unknown record")
+	}
+	if readZoneFile(t, path) != testZone {
+		t.Error("Failed batch changed the zone file")
+	}
+}